@@ -0,0 +1,115 @@
+package gettext
+
+import (
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// convertCharset converts all of the strings of the catalog to UTF-8,
+// according to the charset declared by its Content-Type header. After
+// the conversion, the header declares UTF-8 as the charset. Charsets are
+// looked up by the names that golang.org/x/text/encoding/htmlindex knows.
+//
+// If the charset is not known, strings that are not valid UTF-8 have
+// their invalid bytes replaced by U+FFFD, and an error is returned. A
+// catalog that declares an unknown charset but is valid UTF-8 is used
+// as is, without any error.
+func (p *parseCtx) convertCharset() error {
+	ct := p.po.Header("Content-Type")
+	if ct == "" {
+		return nil
+	}
+
+	_, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return errors.Wrap(err, `po: failed to parse Content-Type header`)
+	}
+
+	// "CHARSET" is the placeholder that is found in templates
+	charset := strings.TrimSpace(params["charset"])
+	if charset == "" || strings.EqualFold(charset, "CHARSET") {
+		return nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		var invalid bool
+		p.po.transcode(func(s string) string {
+			if utf8.ValidString(s) {
+				return s
+			}
+			invalid = true
+			return strings.ToValidUTF8(s, "\uFFFD")
+		})
+		if invalid {
+			return errors.Errorf(`po: unsupported charset %s`, charset)
+		}
+		return nil
+	}
+	if name, _ := htmlindex.Name(enc); name == "utf-8" {
+		return nil
+	}
+
+	dec := enc.NewDecoder()
+	p.po.transcode(func(s string) string {
+		v, err := dec.String(s)
+		if err != nil {
+			return strings.ToValidUTF8(s, "\uFFFD")
+		}
+		return v
+	})
+	p.po.setHeader("Content-Type", "text/plain; charset=UTF-8")
+	return nil
+}
+
+// transcode applies decode to all of the strings of the catalog. The
+// caller must hold the lock if necessary
+func (po *Po) transcode(decode func(string) string) {
+	for i, h := range po.headers {
+		po.headers[i].value = decode(h.value)
+	}
+	po.language = decode(po.language)
+	po.rawHeader = decode(po.rawHeader)
+
+	translations := make(map[string]*translation, len(po.translations))
+	for _, t := range po.translations {
+		t.transcode(decode)
+		translations[t.id] = t
+	}
+	po.translations = translations
+
+	contexts := make(map[string]map[string]*translation, len(po.contexts))
+	for _, m := range po.contexts {
+		for _, t := range m {
+			t.transcode(decode)
+			if _, ok := contexts[t.ctx]; !ok {
+				contexts[t.ctx] = make(map[string]*translation, len(m))
+			}
+			contexts[t.ctx][t.id] = t
+		}
+	}
+	po.contexts = contexts
+
+	for _, t := range po.obsolete {
+		t.transcode(decode)
+	}
+}
+
+func (t *translation) transcode(decode func(string) string) {
+	t.id = decode(t.id)
+	t.ctx = decode(t.ctx)
+	t.PluralID = decode(t.PluralID)
+	for i := range t.Trs {
+		t.Trs[i].text = decode(t.Trs[i].text)
+	}
+	t.PreviousContext = decode(t.PreviousContext)
+	t.PreviousID = decode(t.PreviousID)
+	t.PreviousPluralID = decode(t.PreviousPluralID)
+	for i := range t.Comments {
+		t.Comments[i].Text = decode(t.Comments[i].Text)
+	}
+}
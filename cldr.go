@@ -0,0 +1,299 @@
+package gettext
+
+import "strings"
+
+// CLDR plural categories
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// cldrRule describes the CLDR plural rule for a language. Only the
+// integer part of the rules is implemented, as counts are always integers.
+type cldrRule struct {
+	// categories used by the language, in CLDR order. The position of
+	// a category in this list is the msgstr index that it maps to
+	categories []string
+	category   func(n int) string
+}
+
+// index returns the msgstr index for n
+func (r *cldrRule) index(n int) int {
+	return r.categoryIndex(r.category(n))
+}
+
+// categoryIndex returns the msgstr index for the given category, or -1
+// if the language does not use it
+func (r *cldrRule) categoryIndex(category string) int {
+	for i, c := range r.categories {
+		if c == category {
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	cldrOther = &cldrRule{
+		categories: []string{PluralOther},
+		category: func(n int) string {
+			return PluralOther
+		},
+	}
+	cldrOne = &cldrRule{
+		categories: []string{PluralOne, PluralOther},
+		category: func(n int) string {
+			if n == 1 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	cldrZeroOne = &cldrRule{
+		categories: []string{PluralOne, PluralOther},
+		category: func(n int) string {
+			if n == 0 || n == 1 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	cldrEastSlavic = &cldrRule{
+		categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n%10 == 1 && n%100 != 11:
+				return PluralOne
+			case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+				return PluralFew
+			default:
+				return PluralMany
+			}
+		},
+	}
+	cldrSouthSlavic = &cldrRule{
+		categories: []string{PluralOne, PluralFew, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n%10 == 1 && n%100 != 11:
+				return PluralOne
+			case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+				return PluralFew
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrPolish = &cldrRule{
+		categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n == 1:
+				return PluralOne
+			case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+				return PluralFew
+			default:
+				return PluralMany
+			}
+		},
+	}
+	cldrCzech = &cldrRule{
+		categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n == 1:
+				return PluralOne
+			case n >= 2 && n <= 4:
+				return PluralFew
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrArabic = &cldrRule{
+		categories: []string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n == 0:
+				return PluralZero
+			case n == 1:
+				return PluralOne
+			case n == 2:
+				return PluralTwo
+			case n%100 >= 3 && n%100 <= 10:
+				return PluralFew
+			case n%100 >= 11:
+				return PluralMany
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrHebrew = &cldrRule{
+		categories: []string{PluralOne, PluralTwo, PluralOther},
+		category: func(n int) string {
+			switch n {
+			case 1:
+				return PluralOne
+			case 2:
+				return PluralTwo
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrLithuanian = &cldrRule{
+		categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n%100 >= 11 && n%100 <= 19:
+				return PluralOther
+			case n%10 == 1:
+				return PluralOne
+			case n%10 >= 2:
+				return PluralFew
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrLatvian = &cldrRule{
+		categories: []string{PluralZero, PluralOne, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n%10 == 0 || (n%100 >= 11 && n%100 <= 19):
+				return PluralZero
+			case n%10 == 1:
+				return PluralOne
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrRomanian = &cldrRule{
+		categories: []string{PluralOne, PluralFew, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n == 1:
+				return PluralOne
+			case n == 0 || (n%100 >= 2 && n%100 <= 19):
+				return PluralFew
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrSlovenian = &cldrRule{
+		categories: []string{PluralOne, PluralTwo, PluralFew, PluralOther},
+		category: func(n int) string {
+			switch n % 100 {
+			case 1:
+				return PluralOne
+			case 2:
+				return PluralTwo
+			case 3, 4:
+				return PluralFew
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrIrish = &cldrRule{
+		categories: []string{PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+		category: func(n int) string {
+			switch {
+			case n == 1:
+				return PluralOne
+			case n == 2:
+				return PluralTwo
+			case n >= 3 && n <= 6:
+				return PluralFew
+			case n >= 7 && n <= 10:
+				return PluralMany
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrWelsh = &cldrRule{
+		categories: []string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+		category: func(n int) string {
+			switch n {
+			case 0:
+				return PluralZero
+			case 1:
+				return PluralOne
+			case 2:
+				return PluralTwo
+			case 3:
+				return PluralFew
+			case 6:
+				return PluralMany
+			default:
+				return PluralOther
+			}
+		},
+	}
+	cldrIcelandic = &cldrRule{
+		categories: []string{PluralOne, PluralOther},
+		category: func(n int) string {
+			if n%10 == 1 && n%100 != 11 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+)
+
+// cldrRules maps language codes to their CLDR plural rules
+var cldrRules = map[string]*cldrRule{
+	"ar": cldrArabic,
+	"be": cldrEastSlavic,
+	"bs": cldrSouthSlavic,
+	"cs": cldrCzech,
+	"cy": cldrWelsh,
+	"ga": cldrIrish,
+	"he": cldrHebrew,
+	"hr": cldrSouthSlavic,
+	"is": cldrIcelandic,
+	"lt": cldrLithuanian,
+	"lv": cldrLatvian,
+	"mk": cldrIcelandic,
+	"pl": cldrPolish,
+	"ro": cldrRomanian,
+	"ru": cldrEastSlavic,
+	"sk": cldrCzech,
+	"sl": cldrSlovenian,
+	"sr": cldrSouthSlavic,
+	"uk": cldrEastSlavic,
+}
+
+func init() {
+	for _, lang := range []string{"id", "ja", "km", "ko", "lo", "ms", "my", "th", "vi", "zh"} {
+		cldrRules[lang] = cldrOther
+	}
+	for _, lang := range []string{"am", "bn", "fa", "ff", "fr", "gu", "hi", "hy", "kn", "pt", "zu"} {
+		cldrRules[lang] = cldrZeroOne
+	}
+	for _, lang := range []string{"af", "az", "bg", "ca", "da", "de", "el", "en", "es", "et", "eu", "fi", "gl", "hu", "it", "ka", "kk", "ky", "ml", "mn", "nb", "ne", "nl", "nn", "no", "sq", "sv", "sw", "ta", "te", "tr", "ur", "uz"} {
+		cldrRules[lang] = cldrOne
+	}
+}
+
+// lookupCLDRRule returns the CLDR plural rule for the given language
+// (e.g. "ru" or "pt_BR"), or nil if it is not known
+func lookupCLDRRule(lang string) *cldrRule {
+	lang = NormalizeLang(lang)
+	if r, ok := cldrRules[lang]; ok {
+		return r
+	}
+
+	if i := strings.IndexAny(lang, "_.@"); i > -1 {
+		return cldrRules[lang[:i]]
+	}
+	return nil
+}
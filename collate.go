@@ -0,0 +1,32 @@
+package gettext
+
+import (
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// SortStrings sorts the strings in place, in the order that is expected
+// by speakers of the language of the locale, such as for displaying a
+// sorted list of translated labels. The strings are compared using the
+// collation rules of golang.org/x/text/collate for the language.
+func (l *locale) SortStrings(ss []string) {
+	sortStrings(languageTag(l.normLang), ss)
+}
+
+// SortStrings sorts the strings in place, using the collation rules
+// that are shared by all languages, as a NullLocale has no language.
+func (l NullLocale) SortStrings(ss []string) {
+	sortStrings(language.Und, ss)
+}
+
+// SortStrings sorts the strings in place, in the order that is expected
+// by speakers of the language that was selected.
+func (t *Translator) SortStrings(ss []string) {
+	sortStrings(languageTag(t.lang), ss)
+}
+
+func sortStrings(tag language.Tag, ss []string) {
+	// A Collator is not safe for concurrent use, so a new one is created
+	// for each call
+	collate.New(tag).SortStrings(ss)
+}
@@ -0,0 +1,46 @@
+package gettext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleSortStrings(t *testing.T) {
+	sortStrings := func(lang string, ss []string) {
+		NewLocale(lang, WithSource(NullSource{})).(CollateLocale).SortStrings(ss)
+	}
+
+	ss := []string{"zèbre", "Éclair", "eclair", "abricot", "Œuf", "ecole", "École", "pêche", "peche"}
+	sortStrings("fr_FR", ss)
+	assert.Equal(t, []string{"abricot", "eclair", "Éclair", "ecole", "École", "Œuf", "peche", "pêche", "zèbre"}, ss, `accents and case should only break ties`)
+
+	// Byte order would put "Zebra" first
+	ss = []string{"apple", "Zebra", "banana"}
+	sortStrings("en", ss)
+	assert.Equal(t, []string{"apple", "banana", "Zebra"}, ss)
+
+	// "ö" is a separate letter after "z" in Swedish, but not in German
+	ss = []string{"öl", "zebra", "oxe", "år", "äpple", "ost"}
+	sortStrings("sv_SE", ss)
+	assert.Equal(t, []string{"ost", "oxe", "zebra", "år", "äpple", "öl"}, ss, `Swedish`)
+	sortStrings("de", ss)
+	assert.Equal(t, []string{"äpple", "år", "öl", "ost", "oxe", "zebra"}, ss, `German`)
+
+	ss = []string{"ñu", "nube", "oso", "nz"}
+	sortStrings("es", ss)
+	assert.Equal(t, []string{"nube", "nz", "ñu", "oso"}, ss, `Spanish`)
+
+	ss = []string{"æble", "zebra", "ål", "øl"}
+	sortStrings("da", ss)
+	assert.Equal(t, []string{"zebra", "æble", "øl", "ål"}, ss, `Danish`)
+
+	// Byte order would put the capitals first
+	ss = []string{"Яблоко", "арбуз", "Банан"}
+	sortStrings("ru_RU.UTF-8", ss)
+	assert.Equal(t, []string{"арбуз", "Банан", "Яблоко"}, ss, `Russian`)
+
+	ss = []string{"b", "A", "a"}
+	Locale(NullLocale{}).(CollateLocale).SortStrings(ss)
+	assert.Equal(t, []string{"a", "A", "b"}, ss, `NullLocale`)
+}
@@ -0,0 +1,71 @@
+package gettext
+
+import "sort"
+
+// Diff compares two catalogs, for example the catalogs of two releases,
+// and reports which entries were added to new, which were removed from
+// old, and which have a different translation. An entry is changed if
+// its msgid_plural or any of its msgstr differs. The header and the
+// obsolete entries are not compared. A nil catalog is treated as an
+// empty one.
+//
+// The entries are identified by their msgid. Entries that have a context
+// are identified by the msgctxt and the msgid separated by an EOT byte
+// ("\x04"), as in .mo files. Each list is sorted.
+func Diff(old, new *Po) CatalogDiff {
+	oldMsgs := catalogMessages(old)
+	newMsgs := catalogMessages(new)
+
+	var d CatalogDiff
+	for key, m := range newMsgs {
+		o, ok := oldMsgs[key]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, key)
+		case o.PluralID != m.PluralID || !equalStrings(o.Strings, m.Strings):
+			d.Changed = append(d.Changed, key)
+		}
+	}
+	for key := range oldMsgs {
+		if _, ok := newMsgs[key]; !ok {
+			d.Removed = append(d.Removed, key)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// Empty returns true if the catalogs that were compared have the same
+// entries and translations
+func (d CatalogDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// catalogMessages returns the entries of po, by key
+func catalogMessages(po *Po) map[string]Message {
+	if po == nil {
+		return nil
+	}
+
+	list := po.messages(false)
+	msgs := make(map[string]Message, len(list))
+	for _, m := range list {
+		msgs[contextKey(m.Context, m.ID)] = m
+	}
+	return msgs
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package gettext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	old, err := NewParser().ParseString(`
+msgid ""
+msgstr ""
+"Language: fr\n"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+msgid "Bye"
+msgstr "Au revoir"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+#~ msgid "Gone"
+#~ msgstr "Parti"
+`)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	updated := old.Clone()
+	updated.SetHeader("Language", "fr_FR")
+	updated.Delete("", "Bye")
+	updated.Set("", "Thanks", "", []string{"Merci"})
+	updated.Set("", "One file", "%d files", []string{"Un fichier", "%d dossiers"})
+	updated.Set("menu", "Open", "", []string{"Ouvrez"})
+	updated.Set("", "Hello", "", []string{"Bonjour"})
+
+	d := Diff(old, updated)
+	assert.Equal(t, []string{"Thanks"}, d.Added, `added entries`)
+	assert.Equal(t, []string{"Bye"}, d.Removed, `removed entries`)
+	assert.Equal(t, []string{"One file", "menu\x04Open"}, d.Changed, `changed entries`)
+	assert.False(t, d.Empty(), `Empty should be false`)
+
+	assert.True(t, Diff(old, old.Clone()).Empty(), `identical catalogs should have no differences`)
+	assert.Equal(t, []string{"Bye", "Hello", "One file", "menu\x04Open"}, Diff(old, nil).Removed, `nil catalog should be empty`)
+	assert.Equal(t, []string{"Bye", "Hello", "One file", "menu\x04Open"}, Diff(nil, old).Added, `nil catalog should be empty`)
+}
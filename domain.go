@@ -0,0 +1,50 @@
+package gettext
+
+import "io"
+
+// Name returns the name of the domain
+func (d *Domain) Name() string {
+	return d.name
+}
+
+// Get retrieves the corresponding translation for the given string.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (d *Domain) Get(str string, vars ...interface{}) string {
+	return d.po.Get(str, vars...)
+}
+
+// GetRaw returns the translation of the given string without formatting
+// it, like Po.GetRaw.
+func (d *Domain) GetRaw(str string) string {
+	return d.po.GetRaw(str)
+}
+
+// GetN retrieves the (N)th plural form of translation for the given string.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (d *Domain) GetN(str, plural string, n int, vars ...interface{}) string {
+	return d.po.GetN(str, plural, n, vars...)
+}
+
+// GetC retrieves the corresponding translation for the given string in the given context.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (d *Domain) GetC(str, ctx string, vars ...interface{}) string {
+	return d.po.GetC(str, ctx, vars...)
+}
+
+// GetNC retrieves the (N)th plural form of translation for the given string in the given context.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (d *Domain) GetNC(str, plural string, n int, ctx string, vars ...interface{}) string {
+	return d.po.GetNC(str, plural, n, ctx, vars...)
+}
+
+// Fprintf writes the translation of the given string to w, formatted
+// with the given values, like Po.Fprintf.
+func (d *Domain) Fprintf(w io.Writer, str string, vars ...interface{}) (int, error) {
+	return d.po.Fprintf(w, str, vars...)
+}
+
+// FprintfC writes the translation of the given string in the given
+// context to w, formatted with the given values, like Po.FprintfC.
+func (d *Domain) FprintfC(w io.Writer, str, ctx string, vars ...interface{}) (int, error) {
+	return d.po.FprintfC(w, str, ctx, vars...)
+}
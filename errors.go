@@ -0,0 +1,54 @@
+package gettext
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrLocaleNotFound is returned (wrapped) by LocaleSet.GetLocale when
+	// the locale has not been added to the set
+	ErrLocaleNotFound = errors.New(`locale not found`)
+
+	// ErrDomainNotFound is returned (wrapped) when no catalog can be found
+	// for a domain, e.g. by Locale.AddDomain
+	ErrDomainNotFound = errors.New(`domain not found`)
+
+	// ErrDomainNotLoaded is returned (wrapped) by LookupLocale.GetDErr when
+	// the domain has not been added to the locale
+	ErrDomainNotLoaded = errors.New(`domain not loaded`)
+
+	// ErrGlobNotSupported is returned (wrapped) by DomainLocale.AddDomainGlob
+	// when the Source cannot list files, i.e. is neither a GlobSource nor
+	// a DirSource
+	ErrGlobNotSupported = errors.New(`source does not support listing files`)
+
+	// ErrReadDirNotSupported is returned by GzipSource.ReadDir when the
+	// wrapped Source cannot list directories, i.e. is not a DirSource
+	ErrReadDirNotSupported = errors.New(`source does not support listing directories`)
+)
+
+func (e MultiError) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf(`%d errors occurred: %s`, len(e), strings.Join(msgs, "; "))
+}
+
+// Errors returns the individual errors
+func (e MultiError) Errors() []error {
+	return []error(e)
+}
+
+// Unwrap returns the individual errors, so that errors.Is and errors.As
+// look into each of them
+func (e MultiError) Unwrap() []error {
+	return []error(e)
+}
@@ -0,0 +1,394 @@
+package gettext
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// extractArgs describes the positions of the arguments of each of the
+// lookup methods. -1 means that the method does not take the argument
+var extractArgs = map[string]struct {
+	id, plural, ctx int
+}{
+	"Get":     {id: 0, plural: -1, ctx: -1},
+	"GetN":    {id: 0, plural: 1, ctx: -1},
+	"GetD":    {id: 1, plural: -1, ctx: -1},
+	"TryGetD": {id: 1, plural: -1, ctx: -1},
+	"GetND":   {id: 1, plural: 2, ctx: -1},
+	"GetC":    {id: 0, plural: -1, ctx: 1},
+	"GetNC":   {id: 0, plural: 1, ctx: 3},
+	"GetDC":   {id: 1, plural: -1, ctx: 2},
+	"GetNDC":  {id: 1, plural: 2, ctx: 4},
+}
+
+// extractImportPath is the import path of this package
+const extractImportPath = "github.com/lestrrat-go/gettext"
+
+// extractTypes lists the types of this package whose values are tracked
+// by Extract. Only the calls on values of the types marked true are
+// collected. The other types are tracked because their methods return
+// values of the former
+var extractTypes = map[string]bool{
+	"Locale":           true,
+	"DomainLocale":     true,
+	"LookupLocale":     true,
+	"ContextLocale":    true,
+	"PrinterLocale":    true,
+	"CollateLocale":    true,
+	"NullLocale":       true,
+	"StrictNullLocale": true,
+	"Po":               true,
+	"Domain":           true,
+	"Translator":       true,
+	"LocaleSet":        false,
+	"Parser":           false,
+}
+
+// extractResults maps the functions and methods of this package that
+// return a tracked value to the type of the value
+var extractResults = map[string]string{
+	"NewLocale":             "Locale",
+	"CurrentLocale":         "Locale",
+	"GetLocale":             "Locale",
+	"Match":                 "Locale",
+	"Locale":                "Locale",
+	"NewPo":                 "Po",
+	"NewTemplate":           "Po",
+	"Clone":                 "Po",
+	"Parse":                 "Po",
+	"ParseContext":          "Po",
+	"ParseFile":             "Po",
+	"ParseJSON":             "Po",
+	"ParseMO":               "Po",
+	"ParseMOFile":           "Po",
+	"ParseReader":           "Po",
+	"ParseString":           "Po",
+	"Domain":                "Domain",
+	"Translator":            "Translator",
+	"TranslatorFromContext": "Translator",
+	"NewLocaleSet":          "LocaleSet",
+	"NewParser":             "Parser",
+}
+
+// extractScope records the values of a Go file that have one of the
+// extractTypes. Identifiers are resolved by name within the file,
+// regardless of the scope that they are declared in
+type extractScope struct {
+	pkg   string            // name under which this package is imported
+	vars  map[string]string // type of the variables, parameters and fields
+	funcs map[string]string // result type of the functions and methods of the file
+}
+
+// Extract scans the Go source files under dir, and collects the strings
+// that are passed to the lookup methods of this package (Get, GetN, GetC,
+// GetNC, and their domain variants), like xgettext does. The result is a
+// template, where each entry has an empty translation, and a reference
+// comment ("#:") for each place that it was found in.
+//
+// Only the calls on values whose type is one of the types of this package
+// (Locale, Po, Domain, Translator and the like) are collected, so that
+// e.g. http.Get or Header.Get are ignored. As the files are not fully
+// type-checked, the types are inferred from the declarations of the
+// file: variables, parameters and fields that are declared with these
+// types or assigned the results of the functions of this package (e.g.
+// NewLocale). Files that do not import this package are skipped, as are
+// calls whose arguments are not string literals (or concatenations of
+// string literals). Domain arguments are ignored, so the strings of all domains
+// end up in the same template. Directories named "testdata" or "vendor",
+// and directories whose name starts with "." or "_" are skipped.
+func Extract(dir string) (*Po, error) {
+	po := NewPo()
+	fset := token.NewFileSet()
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := info.Name()
+		if info.IsDir() {
+			if path != dir && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(name) != ".go" {
+			return nil
+		}
+
+		f, err := goparser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return errors.Wrapf(err, `extract: failed to parse %s`, path)
+		}
+
+		scope := newExtractScope(f)
+		if scope == nil {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		rel = filepath.ToSlash(rel)
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			args, ok := extractArgs[sel.Sel.Name]
+			if !ok || !extractTypes[scope.typeOf(sel.X)] {
+				return true
+			}
+
+			id, ok := extractString(call.Args, args.id)
+			if !ok || id == "" {
+				return true
+			}
+
+			var plural, ctx string
+			if args.plural > -1 {
+				if plural, ok = extractString(call.Args, args.plural); !ok {
+					return true
+				}
+			}
+			if args.ctx > -1 {
+				if ctx, ok = extractString(call.Args, args.ctx); !ok {
+					return true
+				}
+			}
+
+			ref := rel + ":" + strconv.Itoa(fset.Position(call.Pos()).Line)
+			po.addTemplateEntry(ctx, id, plural, ref)
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, `extract: failed to scan directory`)
+	}
+	return po, nil
+}
+
+// newExtractScope collects the values of the file f that have one of the
+// extractTypes. It returns nil if f does not import this package
+func newExtractScope(f *ast.File) *extractScope {
+	s := &extractScope{
+		vars:  make(map[string]string),
+		funcs: make(map[string]string),
+	}
+	for _, imp := range f.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err != nil || path != extractImportPath {
+			continue
+		}
+		s.pkg = "gettext"
+		if imp.Name != nil {
+			s.pkg = imp.Name.Name
+		}
+	}
+	if s.pkg == "" || s.pkg == "." || s.pkg == "_" {
+		return nil
+	}
+
+	// Values may be derived from values that are declared later in the
+	// file, so repeat until nothing new is found. Each name only gets
+	// a type once, so this terminates
+	for changed := true; changed; {
+		changed = false
+		set := func(m map[string]string, name, typ string) {
+			if typ != "" && name != "_" && m[name] == "" {
+				m[name] = typ
+				changed = true
+			}
+		}
+		assign := func(names []*ast.Ident, values []ast.Expr) {
+			switch {
+			case len(names) == len(values):
+				for i, name := range names {
+					set(s.vars, name.Name, s.typeOf(values[i]))
+				}
+			case len(values) == 1:
+				// The first result of a function, e.g. "l, err := ..."
+				set(s.vars, names[0].Name, s.typeOf(values[0]))
+			}
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.Field:
+				for _, name := range n.Names {
+					set(s.vars, name.Name, s.typeName(n.Type))
+				}
+			case *ast.ValueSpec:
+				if n.Type != nil {
+					for _, name := range n.Names {
+						set(s.vars, name.Name, s.typeName(n.Type))
+					}
+				} else {
+					assign(n.Names, n.Values)
+				}
+			case *ast.AssignStmt:
+				names := make([]*ast.Ident, 0, len(n.Lhs))
+				for _, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok {
+						names = append(names, id)
+					}
+				}
+				if len(names) == len(n.Lhs) {
+					assign(names, n.Rhs)
+				}
+			case *ast.FuncDecl:
+				if n.Type.Results != nil && len(n.Type.Results.List) > 0 {
+					set(s.funcs, n.Name.Name, s.typeName(n.Type.Results.List[0].Type))
+				}
+			}
+			return true
+		})
+	}
+	return s
+}
+
+// typeName returns the name of the type of this package that the type
+// expression refers to, or an empty string
+func (s *extractScope) typeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return s.typeName(e.X)
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == s.pkg {
+			if _, ok := extractTypes[e.Sel.Name]; ok {
+				return e.Sel.Name
+			}
+		}
+	}
+	return ""
+}
+
+// typeOf returns the name of the type of this package that the value of
+// the expression has, or an empty string if it is not known
+func (s *extractScope) typeOf(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return s.vars[e.Name]
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == s.pkg {
+			return ""
+		}
+		// A field
+		return s.vars[e.Sel.Name]
+	case *ast.CallExpr:
+		switch fun := e.Fun.(type) {
+		case *ast.Ident:
+			return s.funcs[fun.Name]
+		case *ast.SelectorExpr:
+			if pkg, ok := fun.X.(*ast.Ident); ok && pkg.Name == s.pkg {
+				return extractResults[fun.Sel.Name]
+			}
+			if s.typeOf(fun.X) != "" {
+				return extractResults[fun.Sel.Name]
+			}
+			return s.funcs[fun.Sel.Name]
+		}
+	case *ast.ParenExpr:
+		return s.typeOf(e.X)
+	case *ast.StarExpr:
+		return s.typeOf(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return s.typeOf(e.X)
+		}
+	case *ast.CompositeLit:
+		return s.typeName(e.Type)
+	case *ast.TypeAssertExpr:
+		if e.Type != nil {
+			return s.typeName(e.Type)
+		}
+	}
+	return ""
+}
+
+// extractString returns the value of the i-th argument, if it is a
+// constant string expression
+func extractString(args []ast.Expr, i int) (string, bool) {
+	if i >= len(args) {
+		return "", false
+	}
+	return constantString(args[i])
+}
+
+func constantString(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		if err != nil {
+			return "", false
+		}
+		return s, true
+	case *ast.ParenExpr:
+		return constantString(e.X)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, ok := constantString(e.X)
+		if !ok {
+			return "", false
+		}
+		y, ok := constantString(e.Y)
+		if !ok {
+			return "", false
+		}
+		return x + y, true
+	}
+	return "", false
+}
+
+// addTemplateEntry adds an untranslated entry, or adds the reference to
+// the existing entry
+func (po *Po) addTemplateEntry(ctx, id, plural, ref string) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	m := po.translations
+	if ctx != "" {
+		if m = po.contexts[ctx]; m == nil {
+			m = make(map[string]*translation)
+			po.contexts[ctx] = m
+		}
+	}
+
+	t, ok := m[id]
+	if !ok {
+		t = newTranslation()
+		t.id = id
+		t.ctx = ctx
+		m[id] = t
+	}
+
+	if t.PluralID == "" && plural != "" {
+		t.PluralID = plural
+	}
+	if t.PluralID == "" {
+		t.Trs.Set(0, "")
+	} else {
+		t.Trs.Set(1, "")
+		t.Trs.Set(0, "")
+	}
+	t.Comments = append(t.Comments, Comment{Type: CommentReference, Text: ref})
+}
@@ -0,0 +1,146 @@
+package gettext
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gettext-extract")
+	if !assert.NoError(t, err, `TempDir should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"main.go": `package main
+
+import "github.com/lestrrat-go/gettext"
+
+func main() {
+	l := gettext.NewLocale("en")
+	println(l.Get("Hello, world"))
+	println(l.GetN("One file", "%d files", 3, 3))
+	println(l.GetC("Open", "menu"))
+	println(l.GetNDC("dom", "One item", "%d items", 2, "cart", 2))
+	println(l.Get("Long message " +
+		"split in two"))
+	println(l.Get(variable))
+}
+`,
+		"sub/sub.go": `package sub
+
+import gt "github.com/lestrrat-go/gettext"
+
+type server struct {
+	locales *gt.LocaleSet
+	tr      *gt.Translator
+}
+
+func f(l gt.Locale) {
+	l.GetD("dom", "Hello, world")
+	l.GetDC("dom", "Open", "dialog")
+}
+
+func (s *server) g(ctx context.Context) {
+	s.tr.Get("From a field")
+	gt.TranslatorFromContext(ctx).Get("From a context")
+	en, _ := s.locales.GetLocale("en")
+	en.Get("From a set")
+	s.translator().GetN("One result", "%d results", 2, 2)
+}
+
+func (s *server) translator() *gt.Translator {
+	return s.tr
+}
+`,
+		"http.go": `package main
+
+import (
+	"net/http"
+
+	"github.com/lestrrat-go/gettext"
+)
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	resp, _ := http.Get("http://example.com/")
+	h := r.Header
+	h.Get("Content-Type")
+	r.URL.Query().Get("q")
+	gettext.NullLocale{}.Get("Null")
+}
+`,
+		"other/other.go": `package other
+
+func f(l Locale) { l.Get("Not this package") }
+`,
+		"testdata/skip.go": `package skip
+
+func f(l gettext.Locale) { l.Get("Skipped") }
+`,
+		"README.md": `l.Get("Not Go")`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if !assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), `MkdirAll should succeed`) {
+			return
+		}
+		if !assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), `WriteFile should succeed`) {
+			return
+		}
+	}
+
+	po, err := Extract(dir)
+	if !assert.NoError(t, err, `Extract should succeed`) {
+		return
+	}
+
+	m, ok := po.Message("Hello, world")
+	if assert.True(t, ok, `"Hello, world" should be extracted`) {
+		assert.Equal(t, []string{""}, m.Strings)
+		assert.Equal(t, []Comment{
+			{Type: CommentReference, Text: "main.go:7"},
+			{Type: CommentReference, Text: "sub/sub.go:11"},
+		}, m.Comments)
+	}
+
+	m, ok = po.Message("One file")
+	if assert.True(t, ok, `"One file" should be extracted`) {
+		assert.Equal(t, "%d files", m.PluralID)
+		assert.Equal(t, []string{"", ""}, m.Strings)
+	}
+
+	_, ok = po.MessageC("Open", "menu")
+	assert.True(t, ok, `"Open" should be extracted in context menu`)
+	_, ok = po.MessageC("Open", "dialog")
+	assert.True(t, ok, `"Open" should be extracted in context dialog`)
+
+	m, ok = po.MessageC("One item", "cart")
+	if assert.True(t, ok, `"One item" should be extracted in context cart`) {
+		assert.Equal(t, "%d items", m.PluralID)
+	}
+
+	_, ok = po.Message("Long message split in two")
+	assert.True(t, ok, `concatenated strings should be extracted`)
+
+	for _, id := range []string{"From a field", "From a context", "From a set", "One result", "Null"} {
+		_, ok = po.Message(id)
+		assert.True(t, ok, `"`+id+`" should be extracted`)
+	}
+	for _, id := range []string{"http://example.com/", "Content-Type", "q", "Not this package"} {
+		_, ok = po.Message(id)
+		assert.False(t, ok, `"`+id+`" should not be extracted`)
+	}
+
+	_, ok = po.Message("Skipped")
+	assert.False(t, ok, `testdata should be skipped`)
+	_, ok = po.Message("Not Go")
+	assert.False(t, ok, `non-Go files should be skipped`)
+
+	_, err = Extract(filepath.Join(dir, "missing"))
+	assert.Error(t, err, `Extract should fail for missing directories`)
+}
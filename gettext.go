@@ -3,8 +3,191 @@ Package gettext implements GNU gettext utilities.
 */
 package gettext
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
 
+// format formats str using the fmt.Printf syntax. If no values are given,
+// str is returned verbatim, so that messages containing literal '%'
+// characters (e.g. "100%") are not mangled by fmt.Sprintf
 func format(str string, vars ...interface{}) string {
+	if len(vars) == 0 {
+		return str
+	}
 	return fmt.Sprintf(str, vars...)
 }
+
+// fprint writes str to w, formatted the same way as format does
+func fprint(w io.Writer, str string, vars ...interface{}) (int, error) {
+	if len(vars) == 0 {
+		return io.WriteString(w, str)
+	}
+	return fmt.Fprintf(w, str, vars...)
+}
+
+// EscapePercent escapes all '%' characters in s by doubling them, so that
+// s can safely be used as (part of) a format string, and appear literally
+// in the output when values are passed to Get and friends.
+//
+// Translations that contain both verbs and literal percent signs must
+// spell the latter as "%%". Note that when no values are passed, the
+// translation is returned verbatim, and no escaping is necessary.
+func EscapePercent(s string) string {
+	return strings.Replace(s, "%", "%%", -1)
+}
+
+// FormatVerbs returns the verbs of the format string s, in order, as
+// they are written, including their flags, width, precision and
+// argument indexes (e.g. "%d", "%-5.2f", "%[1]s"). Literal percent
+// signs ("%%") and a lone '%' at the end of s are not verbs, and are
+// left out.
+//
+// Comparing the verbs of a msgid with those of its msgstr allows
+// detecting translations that would produce garbled output.
+func FormatVerbs(s string) []string {
+	// argIndex skips an explicit argument index such as "[1]" at i
+	argIndex := func(i int) int {
+		if i < len(s) && s[i] == '[' {
+			if j := strings.IndexByte(s[i:], ']'); j > -1 {
+				return i + j + 1
+			}
+		}
+		return i
+	}
+	// number skips a width or precision at i, either in digits or
+	// given by an argument ("*" or "[n]*")
+	number := func(i int) int {
+		i = argIndex(i)
+		if i < len(s) && s[i] == '*' {
+			return i + 1
+		}
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i
+	}
+
+	var verbs []string
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+
+		start := i
+		for i++; i < len(s) && strings.IndexByte("+-# 0", s[i]) > -1; i++ {
+		}
+		i = number(i)
+		if i < len(s) && s[i] == '.' {
+			i = number(i + 1)
+		}
+		i = argIndex(i)
+		if i >= len(s) {
+			break
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r != '%' {
+			verbs = append(verbs, s[start:i+size])
+		}
+		i += size - 1
+	}
+	return verbs
+}
+
+// formatArgs returns the verb that the format string s applies to each
+// of the values, by position (starting at 0), in the way fmt.Printf
+// assigns them. Values that give a width or a precision ("*") are
+// recorded as '*'. The second return value reports whether s uses
+// explicit argument indexes, in which case fmt does not complain about
+// values that are left unused.
+func formatArgs(s string) (map[int]rune, bool) {
+	args := make(map[int]rune)
+	var argNum int
+	var reordered bool
+	for _, verb := range FormatVerbs(s) {
+		r, size := utf8.DecodeLastRuneInString(verb)
+		body := verb[1 : len(verb)-size]
+		for i := 0; i < len(body); i++ {
+			switch body[i] {
+			case '[':
+				j := strings.IndexByte(body[i:], ']')
+				if j < 0 {
+					break
+				}
+				if n, err := strconv.Atoi(body[i+1 : i+j]); err == nil && n > 0 {
+					argNum = n - 1
+					reordered = true
+				}
+				i += j
+			case '*':
+				args[argNum] = '*'
+				argNum++
+			}
+		}
+		args[argNum] = r
+		argNum++
+	}
+	return args, reordered
+}
+
+// formatsMatch returns true if the translation tr uses the values in the
+// same way as the source string src, so that formatting tr with the
+// values meant for src does not produce errors such as "%!d(string=...)".
+// The translation may reorder the values with explicit argument indexes,
+// and then leave some of them out.
+func formatsMatch(src, tr string) bool {
+	srcArgs, _ := formatArgs(src)
+	trArgs, reordered := formatArgs(tr)
+	for n, verb := range trArgs {
+		if srcArgs[n] != verb {
+			return false
+		}
+	}
+	// Without explicit indexes, the values that are left unused are
+	// reported by fmt as "%!(EXTRA ...)"
+	return reordered || len(trArgs) == len(srcArgs)
+}
+
+// formatNamed substitutes %{name} placeholders in str with the values
+// from the map given as the sole argument. Placeholders whose names are
+// not found in the map are left untouched. If vars does not consist of
+// a single map[string]interface{}, the usual fmt.Sprintf syntax is used.
+func formatNamed(str string, vars ...interface{}) string {
+	if len(vars) != 1 {
+		return format(str, vars...)
+	}
+
+	m, ok := vars[0].(map[string]interface{})
+	if !ok {
+		return format(str, vars...)
+	}
+
+	var buf strings.Builder
+	for {
+		i := strings.Index(str, "%{")
+		if i == -1 {
+			break
+		}
+
+		j := strings.IndexByte(str[i+2:], '}')
+		if j == -1 {
+			break
+		}
+
+		name := str[i+2 : i+2+j]
+		v, ok := m[name]
+		if !ok {
+			buf.WriteString(str[:i+3+j])
+		} else {
+			buf.WriteString(str[:i])
+			fmt.Fprint(&buf, v)
+		}
+		str = str[i+3+j:]
+	}
+	buf.WriteString(str)
+	return buf.String()
+}
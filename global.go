@@ -0,0 +1,174 @@
+package gettext
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The process-global locale used by Gettext and friends
+var (
+	globalMu     sync.RWMutex
+	globalLocale Locale = NullLocale{}
+
+	// Settings of the locale created by SetLanguage. globalLang is empty
+	// if the global locale was set with SetLocale instead
+	globalLang       string
+	globalDirs       = make(map[string]string)
+	globalTextdomain = "default"
+)
+
+// SetLocale sets the process-global locale that is used by Gettext,
+// NGettext and the other package-level functions, which mirror the
+// functions of the C library. This is convenient for small programs,
+// but the Locale should rather be passed around explicitly in libraries
+// and servers. Passing nil restores the initial NullLocale, which
+// returns the strings untranslated. To mirror the C library instead,
+// see SetLanguage, BindTextdomain and Textdomain.
+//
+// SetLocale is safe to call while other goroutines are translating.
+func SetLocale(l Locale) {
+	if l == nil {
+		l = NullLocale{}
+	}
+
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	globalLocale = l
+	globalLang = ""
+}
+
+// SetLanguage sets the process-global locale to a locale for the given
+// language, like setlocale(3) does for LC_MESSAGES. The catalog of each
+// domain is looked for in the directory bound to it with BindTextdomain,
+// or in the current directory, using the default layout (e.g.
+// "<dir>/fr/LC_MESSAGES/<domain>.mo"). Domains are loaded on first use,
+// and the default domain is the one set with Textdomain.
+func SetLanguage(lang string) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	globalLang = lang
+	resetBoundLocale()
+}
+
+// BindTextdomain sets the directory that holds the catalogs of the
+// given domain, like bindtextdomain(3). It affects the global locale if
+// it was set with SetLanguage, now or later.
+func BindTextdomain(domain, dir string) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	globalDirs[domain] = dir
+	resetBoundLocale()
+}
+
+// Textdomain sets the default domain, that is used by Gettext, NGettext,
+// PGettext and NPGettext, like textdomain(3). It affects the global
+// locale if it was set with SetLanguage, now or later. The default
+// domain is initially "default".
+func Textdomain(domain string) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	globalTextdomain = domain
+	resetBoundLocale()
+}
+
+// resetBoundLocale creates the global locale anew, so that it uses the
+// current settings, unless it was set with SetLocale. The catalogs are
+// then loaded again on first use. The caller must hold the lock
+func resetBoundLocale() {
+	if globalLang == "" {
+		return
+	}
+
+	dirs := make(map[string]string, len(globalDirs))
+	for domain, dir := range globalDirs {
+		dirs[domain] = dir
+	}
+
+	globalLocale = NewLocale(globalLang,
+		WithSource(&boundSource{dirs: dirs}),
+		WithDefaultDomain(globalTextdomain),
+		WithLazyDomains(true),
+	)
+}
+
+// ReadFile reads the file from the directory bound to its domain, which
+// is the name of the file without its extension
+func (s *boundSource) ReadFile(f string) ([]byte, error) {
+	domain := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+
+	dir, ok := s.dirs[domain]
+	if !ok {
+		dir = "."
+	}
+	return ioutil.ReadFile(filepath.Join(dir, f))
+}
+
+// CurrentLocale returns the process-global locale that is used by
+// Gettext and friends. It is either the locale passed to SetLocale, or
+// the locale created for the language set with SetLanguage, which uses
+// the directories bound with BindTextdomain and the default domain set
+// with Textdomain, whichever was called last. Initially, it is a
+// NullLocale.
+func CurrentLocale() Locale {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+
+	return globalLocale
+}
+
+// Gettext translates str with the global locale, in its default domain.
+// See Locale.Get.
+func Gettext(str string, vars ...interface{}) string {
+	return CurrentLocale().Get(str, vars...)
+}
+
+// NGettext translates the plural form of str for n with the global
+// locale, in its default domain. See Locale.GetN.
+func NGettext(str, plural string, n int, vars ...interface{}) string {
+	return CurrentLocale().GetN(str, plural, n, vars...)
+}
+
+// DGettext translates str with the global locale, in the given domain.
+// See Locale.GetD.
+func DGettext(dom, str string, vars ...interface{}) string {
+	return CurrentLocale().GetD(dom, str, vars...)
+}
+
+// DNGettext translates the plural form of str for n with the global
+// locale, in the given domain. See Locale.GetND.
+func DNGettext(dom, str, plural string, n int, vars ...interface{}) string {
+	return CurrentLocale().GetND(dom, str, plural, n, vars...)
+}
+
+// PGettext translates str in the given context with the global locale,
+// in its default domain. As in C, the context comes first. See
+// Locale.GetC.
+func PGettext(ctx, str string, vars ...interface{}) string {
+	return CurrentLocale().GetC(str, ctx, vars...)
+}
+
+// NPGettext translates the plural form of str for n in the given
+// context with the global locale, in its default domain. See
+// Locale.GetNC.
+func NPGettext(ctx, str, plural string, n int, vars ...interface{}) string {
+	return CurrentLocale().GetNC(str, plural, n, ctx, vars...)
+}
+
+// DPGettext translates str in the given context with the global locale,
+// in the given domain. See Locale.GetDC.
+func DPGettext(dom, ctx, str string, vars ...interface{}) string {
+	return CurrentLocale().GetDC(dom, str, ctx, vars...)
+}
+
+// DNPGettext translates the plural form of str for n in the given
+// context with the global locale, in the given domain. See
+// Locale.GetNDC.
+func DNPGettext(dom, ctx, str, plural string, n int, vars ...interface{}) string {
+	return CurrentLocale().GetNDC(dom, str, plural, n, ctx, vars...)
+}
@@ -1,8 +1,12 @@
 package gettext
 
 import (
+	"archive/zip"
+	"bufio"
 	"context"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/mattn/kinako/ast"
 )
@@ -18,14 +22,93 @@ type Source interface {
 	ReadFile(string) ([]byte, error)
 }
 
+// GlobSource is a Source that can list the files whose names match a
+// pattern, using the syntax of filepath.Match. It is required by
+// DomainLocale.AddDomainGlob
+type GlobSource interface {
+	Source
+	Glob(string) ([]string, error)
+}
+
+// DirSource is a Source that can list the content of its directories.
+// ReadDir returns the sorted names of the files and directories that are
+// directly under the given directory, "." being the root of the Source.
+// DomainLocale.AddDomainGlob reads the directories of a DirSource to find
+// the catalogs when the Source is not a GlobSource
+type DirSource interface {
+	Source
+	ReadDir(string) ([]string, error)
+}
+
+// dirGlobSource is the GlobSource used for a DirSource that cannot list
+// files by itself
+type dirGlobSource struct {
+	DirSource
+}
+
 type SourceFunc func(string) ([]byte, error)
 
 type FileSystemSource struct {
 	root string
 }
 
-// Locale wraps the entire i18n collection for a single language (locale)
-type Locale interface{
+// WatchingFileSystemSource is a FileSystemSource that periodically
+// polls its root directory, and reports .po and .mo files that have been
+// created, modified, or removed
+type WatchingFileSystemSource struct {
+	FileSystemSource
+	changes   chan string
+	done      chan struct{}
+	closeOnce sync.Once
+	interval  time.Duration
+	files     map[string]fileStat
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+// NullSource is a Source that never finds any file. It is useful
+// when you need a Locale that is not backed by any .po files
+type NullSource struct{}
+
+// MapSource is a Source that serves the content of .po files from
+// memory. The keys are file names as they would be requested by the
+// Locale (e.g. "en/LC_MESSAGES/default.po")
+type MapSource struct {
+	files map[string][]byte
+}
+
+// ZipSource is a Source that serves the content of .po files from a zip
+// archive. The names of the files in the archive follow the same
+// convention as for FileSystemSource (e.g. "en/LC_MESSAGES/default.po")
+type ZipSource struct {
+	files map[string]*zip.File
+}
+
+// boundSource is the Source of the global locale created by SetLanguage.
+// It reads the catalog of each domain from the directory bound to the
+// domain with BindTextdomain
+type boundSource struct {
+	dirs map[string]string // directory of each domain
+}
+
+// GzipSource is a Source that decompresses the gzip-compressed files
+// returned by another Source. Files that are not compressed are returned
+// as is
+type GzipSource struct {
+	src Source
+}
+
+// Locale wraps the entire i18n collection for a single language (locale).
+//
+// The locales created by NewLocale, as well as NullLocale, also implement
+// DomainLocale, LookupLocale, ContextLocale, PrinterLocale and
+// CollateLocale. Other
+// implementations may type-assert to these interfaces to use the
+// additional features when they are available.
+type Locale interface {
 	AddDomain(string) error
 	Get(string, ...interface{}) string
 	GetN(string, string, int, ...interface{}) string
@@ -37,43 +120,228 @@ type Locale interface{
 	GetNDC(string, string, string, int, string, ...interface{}) string
 }
 
+// DomainLocale is a Locale that reports its language, and whose domains
+// can be listed, inspected and removed
+type DomainLocale interface {
+	Locale
+	AddDomainGlob(string) error
+	RemoveDomain(string)
+	Domains() []string
+	HasDomain(string) bool
+	Domain(string) (*Domain, bool)
+	DomainSource(string) string
+	SetDefaultDomain(string) error
+	Lang() string
+}
+
+// LookupLocale is a Locale that can return translations without
+// formatting them, and report the translations that are missing
+type LookupLocale interface {
+	Locale
+	GetRaw(string) string
+	GetRawD(string, string) string
+	TryGetD(string, string, ...interface{}) (string, bool)
+	GetDErr(string, string, ...interface{}) (string, error)
+}
+
+// ContextLocale is a Locale that honors the cancellation of a
+// context.Context when a domain has to be loaded lazily
+type ContextLocale interface {
+	Locale
+	GetContext(context.Context, string, ...interface{}) string
+	GetDContext(context.Context, string, string, ...interface{}) string
+}
+
+// PrinterLocale is a Locale that can write translations directly to an
+// io.Writer
+type PrinterLocale interface {
+	Locale
+	Fprintf(io.Writer, string, ...interface{}) (int, error)
+	FprintfD(io.Writer, string, string, ...interface{}) (int, error)
+	FprintfC(io.Writer, string, string, ...interface{}) (int, error)
+	FprintfDC(io.Writer, string, string, string, ...interface{}) (int, error)
+}
+
+// CollateLocale is a Locale that can sort strings in the order that is
+// expected by speakers of its language
+type CollateLocale interface {
+	Locale
+	SortStrings([]string)
+}
+
+// Domain is a single domain of a Locale. It provides the same lookup
+// methods as Locale, without the domain argument.
+type Domain struct {
+	name string
+	po   *Po
+}
+
+// MultiError is returned when several operations failed, such as when
+// multiple domains could not be loaded. Each element describes one failure.
+type MultiError []error
+
+// missingDomainError is returned when no catalog can be found for a
+// domain
+type missingDomainError struct {
+	domain string
+	lang   string
+}
+
+// Translator is a Locale that has been resolved from a list of preferred
+// languages. It is meant to be created once per request (or any other
+// unit of work) and carried around, for example in a context.Context.
+type Translator struct {
+	lang   string
+	locale Locale
+}
+
 type locale struct {
-	lang          string // Language for this Locale
-	defaultDomain string
-	domains       map[string]*Po // List of available domains for this locale.
-	src           Source
-	mu            sync.RWMutex
+	lang              string // Language for this Locale, as specified by the user
+	base              Locale // consulted when a string has no translation, if not nil
+	normLang          string // Normalized language name, used for lookups
+	defaultDomain     string
+	layout            []string       // if nil, defaultLayout is used
+	lazy              bool           // load unknown domains on first use
+	lazyDomains       sync.Map       // domain name -> *lazyDomain
+	domains           map[string]*Po // List of available domains for this locale.
+	namedPlaceholders bool
+	options           []Option // passed to NewParser
+	parser            *Parser  // if nil, NewParser(options...) is used
+	src               Source
+	mu                sync.RWMutex
+}
+
+// lazyDomain records the loading of a domain on first use
+type lazyDomain struct {
+	done chan struct{} // closed when the loading is complete
+	err  error         // error from the loading, if any. Set before done is closed
 }
 
 // Po stores content required for translation, and does the grunt work of
 // producing localized strings.
 //
-// Once created you cannot alter the object. You will have to create a new
-// one yourself.
+// Once created, the object may only be altered through its methods: Set
+// and Delete for the entries, SetHeader, SetPluralForms and SetPluralFunc
+// for the headers and the plural rules, and UnmarshalJSON to replace the
+// whole content. All of them are safe to call while other goroutines are
+// looking up translations. Once the object is sealed, SetPluralForms and
+// UnmarshalJSON fail, and the other methods have no effect.
 type Po struct {
-	language     string // Language header
-	pluralForms  string // Plural-Forms header
-	nplurals     int    // Parsed Plural-Forms header values
+	mu           sync.RWMutex
+	sealed       int32    // accessed atomically. If non-zero, mu is not used by readers
+	language     string   // Language header
+	pluralForms  string   // Plural-Forms header
+	headers      []header // all headers, in order
+	rawHeader    string   // msgstr of the header entry, as parsed
+	nplurals     int      // Parsed Plural-Forms header values
 	plural       []ast.Stmt
+	pluralSrc    string        // source of the plural formula
+	pluralErr    error         // error from compiling the plural formula, if any
+	pluralFunc   func(int) int // if set, used instead of the formula
 	translations map[string]*translation
 	contexts     map[string]map[string]*translation
+	obsolete     []*translation // obsolete (#~) entries, in file order
+	duplicates   []string       // keys of the entries that were defined more than once
+
+	namedPlaceholders     bool // use %{name} instead of fmt.Printf syntax
+	safeFormat            bool // fall back to msgid if msgstr verbs do not match
+	formatMismatchHandler func(string, string)
+	missingHandler        func(string) string // called instead of formatting the source string on a miss
+	cldrPlurals           bool                // use CLDR plural rules instead of Plural-Forms
+	contextFallback       bool                // use translations without context as fallback
+	filename              string              // name of the file loaded by a Locale, if any
 }
 
+// header is a single header of a catalog, such as "Language: ja"
+type header struct {
+	name  string
+	value string
+}
+
+// Message is a read-only snapshot of a single entry in a catalog
+type Message struct {
+	Context  string   // msgctxt, if any
+	ID       string   // msgid
+	PluralID string   // msgid_plural, if any
+	Strings  []string // msgstr, or msgstr[n] for plural entries
+
+	// Values from "#|" comments, recorded by msgmerge for fuzzy entries
+	PreviousContext  string
+	PreviousID       string
+	PreviousPluralID string
+
+	Comments []Comment // comment lines preceding the entry, in order
+}
+
+// Comment is a single comment line of an entry in a catalog
+type Comment struct {
+	Type string // one of the Comment* constants
+	Text string // the rest of the line after the marker and one space
+}
+
+// CatalogDiff is the result of Diff. Each list holds the keys of the
+// entries, sorted
+type CatalogDiff struct {
+	Added   []string // entries that are only in the new catalog
+	Removed []string // entries that are only in the old catalog
+	Changed []string // entries whose translation differs
+}
+
+// Types of comments, by the marker that they start with
+const (
+	CommentTranslator = "translator" // "# "
+	CommentExtracted  = "extracted"  // "#."
+	CommentReference  = "reference"  // "#:"
+	CommentFlag       = "flag"       // "#,"
+	CommentPrevious   = "previous"   // "#|"
+	CommentObsolete   = "obsolete"   // "#~|", previous values of an obsolete entry
+)
+
 // Parser parses .po files and creates new Po objects
 type Parser struct {
-	strict bool
+	cldrPlurals           bool
+	contextFallback       bool
+	formatMismatchHandler func(string, string)
+	missingHandler        func(string) string
+	namedPlaceholders     bool
+	safeFormat            bool
+	strict                bool
+	trimSpace             bool
+}
+
+// ParseError is the error returned by the Parser in strict mode when
+// it encounters malformed content. It carries the location of the
+// offending line so that it can be handled programmatically.
+type ParseError struct {
+	Line    int    // 1-based line number
+	Message string // description of what failed
+	err     error
 }
 
 // internally used to parse po files
 type parseCtx struct {
 	context.Context
-	buf            []byte
+	scanner        *bufio.Scanner // source of the lines, if reading from an io.Reader
+	data           string         // otherwise, the remaining lines
+	text           string         // current line
 	po             *Po
-	pos            int
+	line           int // current line number, 1-based
 	rawHeaders     string
+	headerLine     int // line where the headers started
 	strict         bool
+	trimSpace      bool // trim spaces around msgstr values
 	curTranslation *translation
 	curContext     string
+	curLine        int    // line where the current entry started
+	dupErr         error  // duplicate entry found in strict mode, if any
+	curIndex       int    // index of the msgstr that was set last
+	curField       int    // field that continuation lines extend (field* constants)
+	obsolete       bool   // true if the current line is an obsolete (#~) line
+	prevContext    string // "#| msgctxt" for the upcoming entry
+	prevID         string // "#| msgid" for the upcoming entry
+	prevPluralID   string // "#| msgid_plural" for the upcoming entry
+	prevField      *string
+	comments       []Comment // comments for the upcoming entry
 }
 
 type Option interface {
@@ -88,9 +356,27 @@ type option struct {
 
 type translation struct {
 	id       string
+	ctx      string
 	PluralID string
 	Trs      textlist
+	single   [1]textentry // storage for Trs when there is only one form
+	obsolete bool
+
+	// Values recorded by msgmerge in "#|" comments
+	PreviousContext  string
+	PreviousID       string
+	PreviousPluralID string
+
+	// All of the comment lines that preceded the entry, in order
+	Comments []Comment
 }
 
-// one translation object may contain multiple translations
-type textlist []string
+// one translation object may contain multiple translations, indexed
+// by their plural form. Forms may be stored in any order, so the list
+// may contain gaps for forms that were never set
+type textlist []textentry
+
+type textentry struct {
+	text  string
+	valid bool // false if this form was never set
+}
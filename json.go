@@ -0,0 +1,256 @@
+package gettext
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// poJSON is the JSON representation of a catalog
+type poJSON struct {
+	Headers  map[string]string   `json:"headers"`
+	Messages map[string][]string `json:"messages"`
+	Plurals  map[string]string   `json:"plurals,omitempty"`
+}
+
+// MarshalJSON encodes the catalog as a JSON object, for use in web
+// frontends and the like. The object has the following fields:
+//
+//	headers   the headers of the catalog, by name
+//	messages  the forms of each entry, by key
+//	plurals   the msgid_plural of each plural entry, by key
+//
+// The key of an entry is its msgid, prefixed by its msgctxt and "\u0004"
+// if it has a context, as in .mo files. Forms that are not set are
+// encoded as empty strings. Comments and obsolete entries are not
+// included.
+func (po *Po) MarshalJSON() ([]byte, error) {
+	defer po.runlock(po.rlock())
+
+	v := poJSON{
+		Headers:  make(map[string]string, len(po.headers)),
+		Messages: make(map[string][]string, len(po.translations)),
+		Plurals:  make(map[string]string),
+	}
+	for _, h := range po.headers {
+		v.Headers[h.name] = h.value
+	}
+
+	add := func(t *translation) {
+		key := contextKey(t.ctx, t.id)
+		forms := make([]string, t.Trs.Len())
+		for i := range forms {
+			forms[i], _ = t.Trs.Get(i)
+		}
+		v.Messages[key] = forms
+		if t.PluralID != "" {
+			v.Plurals[key] = t.PluralID
+		}
+	}
+	for _, t := range po.translations {
+		add(t)
+	}
+	for _, m := range po.contexts {
+		for _, t := range m {
+			add(t)
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON replaces the content of the catalog with the JSON object
+// created by MarshalJSON. The options of the catalog, such as those set
+// by the Parser, are left untouched, but what was recorded when parsing
+// it (see RawHeader and Duplicates) is cleared. It fails if the Po object
+// has been sealed.
+func (po *Po) UnmarshalJSON(data []byte) error {
+	var v poJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.Wrap(err, `po: failed to decode JSON`)
+	}
+
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	if po.Sealed() {
+		return errors.New(`po: cannot modify a sealed Po`)
+	}
+
+	po.translations = make(map[string]*translation, len(v.Messages))
+	po.contexts = make(map[string]map[string]*translation)
+	po.obsolete = nil
+	po.duplicates = nil
+	po.headers = nil
+	po.rawHeader = ""
+	po.language, po.pluralForms = "", ""
+	po.nplurals, po.plural, po.pluralSrc, po.pluralErr = 0, nil, "", nil
+
+	// Headers are applied in a fixed order, as JSON objects are unordered
+	names := make([]string, 0, len(v.Headers))
+	for name := range v.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		po.updateHeader(name, v.Headers[name])
+	}
+	if po.pluralForms == "" {
+		po.parsePluralForms(lookupPluralForms(po.language))
+	}
+
+	for key, forms := range v.Messages {
+		t := newTranslation()
+		t.ctx, t.id = splitContextKey(key)
+		t.PluralID = v.Plurals[key]
+		for i, form := range forms {
+			t.Trs.Set(i, form)
+		}
+		po.add(t)
+	}
+	return nil
+}
+
+// jedHeaders maps the names of the header fields that are used by Jed
+// and gettext.js to the names of the corresponding .po headers
+var jedHeaders = map[string]string{
+	"lang":         "Language",
+	"language":     "Language",
+	"plural_forms": "Plural-Forms",
+	"plural-forms": "Plural-Forms",
+}
+
+// ParseJSON parses a catalog in the JSON format used by JavaScript
+// libraries such as Jed and gettext.js. Both the Jed 1.x format, where
+// the messages are found under "locale_data", and the flat format, where
+// the top level object holds the messages, are supported:
+//
+//	{"": {"language": "fr", "plural-forms": "nplurals=2; plural=(n > 1);"},
+//	 "Hello": "Bonjour",
+//	 "One file": ["Un fichier", "%d fichiers"],
+//	 "menu\u0004Open": ["Ouvrir"]}
+//
+// The entry with the empty key holds the headers. Translations are
+// either a string or an array of plural forms. The Jed 0.x format, where
+// each array starts with the msgid_plural or null, is recognized when
+// any of the arrays starts with null.
+//
+// Errors in the headers are only reported if strict parsing is enabled.
+func (p *Parser) ParseJSON(data []byte) (*Po, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, errors.Wrap(err, `json: failed to decode catalog`)
+	}
+
+	messages := top
+	if raw, ok := top["locale_data"]; ok {
+		var domains map[string]map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &domains); err != nil {
+			return nil, errors.Wrap(err, `json: failed to decode locale_data`)
+		}
+
+		// Use the domain named by "domain", or the first one
+		var domain string
+		if raw, ok := top["domain"]; ok {
+			json.Unmarshal(raw, &domain)
+		}
+		if _, ok := domains[domain]; !ok {
+			names := make([]string, 0, len(domains))
+			for name := range domains {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if len(names) == 0 {
+				return nil, errors.New(`json: locale_data has no domains`)
+			}
+			domain = names[0]
+		}
+		messages = domains[domain]
+	}
+
+	ctx := p.newParseCtx(context.Background(), len(messages))
+
+	keys := make([]string, 0, len(messages))
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make(map[string][]*string, len(messages))
+	var jed0 bool
+	for _, key := range keys {
+		raw := messages[key]
+		if key == "" {
+			var h map[string]string
+			if err := json.Unmarshal(raw, &h); err != nil {
+				return nil, errors.Wrap(err, `json: failed to decode header entry`)
+			}
+			names := make([]string, 0, len(h))
+			for name := range h {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if v, ok := jedHeaders[strings.ToLower(name)]; ok {
+					ctx.rawHeaders += v + ": " + h[name] + "\n"
+				}
+			}
+			continue
+		}
+
+		var forms []*string
+		if len(raw) > 0 && raw[0] == '"' {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, errors.Wrapf(err, `json: failed to decode translation of %s`, strconv.Quote(key))
+			}
+			forms = []*string{&s}
+		} else if err := json.Unmarshal(raw, &forms); err != nil {
+			return nil, errors.Wrapf(err, `json: failed to decode translation of %s`, strconv.Quote(key))
+		}
+
+		// In the Jed 0.x format, the forms are preceded by the
+		// msgid_plural, or null for entries that are not plural
+		if len(forms) > 1 && forms[0] == nil {
+			jed0 = true
+		}
+		entries[key] = forms
+	}
+
+	for _, key := range keys {
+		forms, ok := entries[key]
+		if !ok {
+			continue
+		}
+
+		t := newTranslation()
+		t.ctx, t.id = splitContextKey(key)
+		if jed0 && len(forms) > 0 {
+			if forms[0] != nil {
+				t.PluralID = *forms[0]
+			}
+			forms = forms[1:]
+		}
+		for i, form := range forms {
+			if form != nil {
+				t.Trs.Set(i, *form)
+			}
+		}
+
+		ctx.curTranslation = t
+		ctx.curContext = t.ctx
+		ctx.pop()
+	}
+
+	if err := ctx.parseHeaders(); err != nil {
+		if p.strict {
+			return nil, errors.Wrap(err, `json: failed to parse header`)
+		}
+	}
+
+	return ctx.po, nil
+}
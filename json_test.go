@@ -0,0 +1,178 @@
+package gettext
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoJSON(t *testing.T) {
+	str := `msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+#~ msgid "Obsolete"
+#~ msgstr "Obsolète"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	data, err := json.Marshal(po)
+	if !assert.NoError(t, err, `json.Marshal should succeed`) {
+		return
+	}
+
+	var v map[string]interface{}
+	if !assert.NoError(t, json.Unmarshal(data, &v), `output should be valid JSON`) {
+		return
+	}
+	expected := map[string]interface{}{
+		"headers": map[string]interface{}{
+			"Language":     "fr",
+			"Plural-Forms": "nplurals=2; plural=(n > 1);",
+		},
+		"messages": map[string]interface{}{
+			"Hello":        []interface{}{"Bonjour"},
+			"One file":     []interface{}{"Un fichier", "%d fichiers"},
+			"menu\x04Open": []interface{}{"Ouvrir"},
+		},
+		"plurals": map[string]interface{}{
+			"One file": "%d files",
+		},
+	}
+	assert.Equal(t, expected, v)
+
+	var c Po
+	if !assert.NoError(t, json.Unmarshal(data, &c), `json.Unmarshal should succeed`) {
+		return
+	}
+	assert.Equal(t, "fr", c.Language())
+	assert.Equal(t, "Bonjour", c.Get("Hello"))
+	assert.Equal(t, "Un fichier", c.GetN("One file", "%d files", 1))
+	assert.Equal(t, "2 fichiers", c.GetN("One file", "%d files", 2, 2))
+	assert.Equal(t, "Ouvrir", c.GetC("Open", "menu"))
+	id, ok := c.PluralID("One file")
+	assert.True(t, ok, `msgid_plural should round-trip`)
+	assert.Equal(t, "%d files", id)
+
+	again, err := json.Marshal(&c)
+	if assert.NoError(t, err, `json.Marshal should succeed`) {
+		assert.Equal(t, string(data), string(again), `output should round-trip`)
+	}
+
+	// Unmarshaling replaces the existing content
+	if assert.NoError(t, json.Unmarshal([]byte(`{"headers":{"Language":"ja"},"messages":{"Hello":["こんにちは"]}}`), &c)) {
+		assert.Equal(t, "こんにちは", c.Get("Hello"))
+		assert.Equal(t, "Open", c.GetC("Open", "menu"), `old entries should be removed`)
+		assert.Equal(t, 1, c.NPlurals(), `default plural forms should be used for the language`)
+	}
+
+	assert.Error(t, json.Unmarshal([]byte(`{"messages":[]}`), &c), `invalid JSON should be rejected`)
+
+	// What was recorded when parsing is cleared
+	parsed, err := NewParser().ParseString(str + `
+msgid "Hello"
+msgstr "Salut"
+`)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.NotEmpty(t, parsed.Duplicates(), `duplicates should be recorded`)
+	assert.NotEmpty(t, parsed.RawHeader(), `raw header should be recorded`)
+	if assert.NoError(t, json.Unmarshal(data, parsed), `json.Unmarshal should succeed`) {
+		assert.Empty(t, parsed.Duplicates(), `duplicates should be cleared`)
+		assert.Empty(t, parsed.RawHeader(), `raw header should be cleared`)
+	}
+
+	po.Seal()
+	assert.Error(t, po.UnmarshalJSON(data), `sealed Po should not be modified`)
+}
+
+func TestParseJSON(t *testing.T) {
+	testcases := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "gettext.js",
+			data: `{
+  "": {"language": "fr", "plural-forms": "nplurals=2; plural=(n > 1);"},
+  "Hello": "Bonjour",
+  "One file": ["Un fichier", "%d fichiers"],
+  "menu\u0004Open": ["Ouvrir"]
+}`,
+		},
+		{
+			name: "Jed 1.x",
+			data: `{
+  "domain": "messages",
+  "locale_data": {
+    "messages": {
+      "": {"domain": "messages", "lang": "fr", "plural_forms": "nplurals=2; plural=(n > 1);"},
+      "Hello": ["Bonjour"],
+      "One file": ["Un fichier", "%d fichiers"],
+      "menu\u0004Open": ["Ouvrir"]
+    }
+  }
+}`,
+		},
+		{
+			name: "Jed 0.x",
+			data: `{
+  "locale_data": {
+    "messages": {
+      "": {"lang": "fr", "plural_forms": "nplurals=2; plural=(n > 1);"},
+      "Hello": [null, "Bonjour"],
+      "One file": ["%d files", "Un fichier", "%d fichiers"],
+      "menu\u0004Open": [null, "Ouvrir"]
+    }
+  }
+}`,
+		},
+	}
+
+	for _, tc := range testcases {
+		po, err := NewParser(WithStrictParsing(true)).ParseJSON([]byte(tc.data))
+		if !assert.NoError(t, err, `ParseJSON should succeed for `+tc.name) {
+			continue
+		}
+		assert.Equal(t, "fr", po.Language(), `language for `+tc.name)
+		assert.Equal(t, "nplurals=2; plural=(n > 1);", po.Header("Plural-Forms"), `plural forms for `+tc.name)
+		assert.Equal(t, "Bonjour", po.Get("Hello"), `Get for `+tc.name)
+		assert.Equal(t, "Un fichier", po.GetN("One file", "%d files", 1), `GetN(1) for `+tc.name)
+		assert.Equal(t, "Un fichier", po.GetN("One file", "%d files", 0), `GetN(0) for `+tc.name)
+		assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2), `GetN(2) for `+tc.name)
+		assert.Equal(t, "Ouvrir", po.GetC("Open", "menu"), `GetC for `+tc.name)
+	}
+
+	po, err := NewParser().ParseJSON([]byte(`{"": {"language": "ja"}, "Hello": "こんにちは"}`))
+	if assert.NoError(t, err, `ParseJSON should succeed`) {
+		assert.Equal(t, 1, po.NPlurals(), `default plural forms should be used for the language`)
+	}
+
+	_, err = NewParser().ParseJSON([]byte(`["Hello"]`))
+	assert.Error(t, err, `non-object should be rejected`)
+	_, err = NewParser().ParseJSON([]byte(`{"Hello": 1}`))
+	assert.Error(t, err, `invalid translation should be rejected`)
+	_, err = NewParser().ParseJSON([]byte(`{"locale_data": {}}`))
+	assert.Error(t, err, `empty locale_data should be rejected`)
+	_, err = NewParser(WithStrictParsing(true)).ParseJSON([]byte(`{"": {"plural-forms": "nplurals=2; plural=(n > ;"}}`))
+	assert.Error(t, err, `invalid plural forms should be rejected in strict mode`)
+}
@@ -0,0 +1,147 @@
+package gettext
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DetectLocale inspects the LC_ALL, LC_MESSAGES, and LANG environment
+// variables (in that order) and returns the locale name specified by
+// the first one that is set. The charset (e.g. ".UTF-8") and the
+// modifier (e.g. "@euro") parts of the name are stripped.
+//
+// If none of the variables are set, an empty string is returned.
+func DetectLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return stripLocaleSuffix(v)
+		}
+	}
+	return ""
+}
+
+// stripLocaleSuffix removes the charset and modifier parts of a
+// locale name such as "de_DE.UTF-8@euro"
+func stripLocaleSuffix(s string) string {
+	if i := strings.IndexAny(s, ".@"); i > -1 {
+		return s[:i]
+	}
+	return s
+}
+
+// languageTag converts a locale name such as "pt_BR.UTF-8" to the
+// corresponding language tag of golang.org/x/text/language. The charset
+// and modifier parts of the name are ignored. language.Und is returned
+// for names that are not valid language tags.
+func languageTag(s string) language.Tag {
+	tag, err := language.Parse(strings.Replace(stripLocaleSuffix(s), "_", "-", -1))
+	if err != nil {
+		return language.Und
+	}
+	return tag
+}
+
+// NormalizeLang canonicalizes a locale name to the form used by gettext,
+// which is "ll_CC": a lowercase language code, followed by an underscore,
+// followed by an uppercase territory code. For example, "en-us", "EN_us"
+// and "en_US" are all normalized to "en_US". Script codes are title-cased
+// as in BCP 47, so "zh-hant-tw" is normalized to "zh_Hant_TW".
+//
+// The charset and modifier parts of the name, if any, are preserved.
+func NormalizeLang(s string) string {
+	var suffix string
+	if i := strings.IndexAny(s, ".@"); i > -1 {
+		s, suffix = s[:i], s[i:]
+	}
+
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	if len(parts) == 0 {
+		return suffix
+	}
+
+	parts[0] = strings.ToLower(parts[0])
+	for i, part := range parts[1:] {
+		switch {
+		case len(part) == 4 && isAlpha(part):
+			// Script, e.g. "Hant" or "Latn"
+			parts[i+1] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		case len(part) == 2 && isAlpha(part), len(part) == 3 && isDigit(part):
+			// Region, e.g. "TW" or "419"
+			parts[i+1] = strings.ToUpper(part)
+		}
+	}
+	return strings.Join(parts, "_") + suffix
+}
+
+// isAlpha returns true if s only contains ASCII letters
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDigit returns true if s only contains ASCII digits
+func isDigit(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLocale splits a locale name such as "sr_RS.UTF-8@latin" into
+// its language ("sr"), territory ("RS"), charset ("UTF-8") and modifier
+// ("latin") parts. Missing parts are returned as empty strings.
+func splitLocale(s string) (language, territory, charset, modifier string) {
+	if i := strings.IndexByte(s, '@'); i > -1 {
+		s, modifier = s[:i], s[i+1:]
+	}
+	if i := strings.IndexByte(s, '.'); i > -1 {
+		s, charset = s[:i], s[i+1:]
+	}
+	if i := strings.IndexAny(s, "_-"); i > -1 {
+		s, territory = s[:i], s[i+1:]
+	}
+	return s, territory, charset, modifier
+}
+
+// localeVariants returns the names that are tried, in order, when looking
+// for the catalogs of the given locale. The first list contains the full
+// name, the name without the charset, and the name without the charset
+// and the modifier. The second list contains the language code with the
+// modifier, and the language code alone. Duplicates are removed.
+func localeVariants(s string) ([]string, []string) {
+	language, territory, _, modifier := splitLocale(s)
+
+	base := language
+	if territory != "" {
+		base += "_" + territory
+	}
+
+	var full, lang []string
+	add := func(list []string, v string) []string {
+		for _, x := range list {
+			if x == v {
+				return list
+			}
+		}
+		return append(list, v)
+	}
+
+	full = add(full, s)
+	if modifier != "" {
+		full = add(full, base+"@"+modifier)
+		lang = add(lang, language+"@"+modifier)
+	}
+	full = add(full, base)
+	lang = add(lang, language)
+	return full, lang
+}
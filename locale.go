@@ -1,8 +1,13 @@
 package gettext
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -13,8 +18,70 @@ func (l NullLocale) AddDomain(_ string) error {
 	return nil
 }
 
+// StrictNullLocale is a NullLocale whose AddDomain and AddDomainGlob
+// methods fail. It is meant to be used during development and in tests,
+// to catch code that operates on a null locale by mistake. Use
+// NullLocale as the lenient fallback in production.
+type StrictNullLocale struct {
+	NullLocale
+}
+
+func (l StrictNullLocale) AddDomain(dom string) error {
+	return errors.Errorf(`locale: cannot add domain %s to a null locale`, dom)
+}
+
+func (l StrictNullLocale) AddDomainGlob(pattern string) error {
+	return errors.Errorf(`locale: cannot add domains matching %s to a null locale`, pattern)
+}
+
+func (l NullLocale) AddDomainGlob(_ string) error {
+	return nil
+}
+
+func (l NullLocale) Lang() string {
+	return ""
+}
+
+func (l NullLocale) RemoveDomain(_ string) {}
+
+func (l NullLocale) Domains() []string {
+	return nil
+}
+
+func (l NullLocale) HasDomain(_ string) bool {
+	return false
+}
+
+func (l NullLocale) Domain(_ string) (*Domain, bool) {
+	return nil, false
+}
+
+func (l NullLocale) DomainSource(_ string) string {
+	return ""
+}
+
+func (l NullLocale) SetDefaultDomain(dom string) error {
+	return errors.Errorf(`locale: domain %s is not loaded`, dom)
+}
+
 func (l NullLocale) Get(s string, args ...interface{}) string {
-	return fmt.Sprintf(s, args...)
+	return format(s, args...)
+}
+
+func (l NullLocale) GetRaw(s string) string {
+	return s
+}
+
+func (l NullLocale) GetRawD(_ string, s string) string {
+	return s
+}
+
+func (l NullLocale) GetContext(_ context.Context, s string, args ...interface{}) string {
+	return l.Get(s, args...)
+}
+
+func (l NullLocale) GetDContext(_ context.Context, _ string, s string, args ...interface{}) string {
+	return l.Get(s, args...)
 }
 
 func (l NullLocale) GetC(str string, _ string, vars ...interface{}) string {
@@ -25,6 +92,16 @@ func (l NullLocale) GetD(_ string, str string, vars ...interface{}) string {
 	return l.Get(str, vars...)
 }
 
+// GetDErr returns the formatted string, with an error wrapping
+// ErrDomainNotFound, as a NullLocale has no domains
+func (l NullLocale) GetDErr(dom string, str string, vars ...interface{}) (string, error) {
+	return l.Get(str, vars...), errors.Wrapf(ErrDomainNotFound, `locale: no domain %s in a null locale`, dom)
+}
+
+func (l NullLocale) TryGetD(_ string, str string, vars ...interface{}) (string, bool) {
+	return l.Get(str, vars...), false
+}
+
 func (l NullLocale) GetDC(_ string, str, _ string, vars ...interface{}) string {
 	return l.Get(str, vars...)
 }
@@ -45,20 +122,63 @@ func (l NullLocale) GetNDC(_ string, str string, _ string, _ int, _ string, vars
 	return l.Get(str, vars...)
 }
 
+func (l NullLocale) Fprintf(w io.Writer, str string, vars ...interface{}) (int, error) {
+	return fprint(w, str, vars...)
+}
+
+func (l NullLocale) FprintfD(w io.Writer, _ string, str string, vars ...interface{}) (int, error) {
+	return l.Fprintf(w, str, vars...)
+}
+
+func (l NullLocale) FprintfC(w io.Writer, str string, _ string, vars ...interface{}) (int, error) {
+	return l.Fprintf(w, str, vars...)
+}
+
+func (l NullLocale) FprintfDC(w io.Writer, _ string, str string, _ string, vars ...interface{}) (int, error) {
+	return l.Fprintf(w, str, vars...)
+}
+
 // NewLocale creates and initializes a new Locale object for a given language.
+// The language name is normalized using NormalizeLang before being used
+// to look up .po files, so "en-us" and "en_US" are treated the same.
 //
 // Possible options include:
 // * WithSource: specifies where to load the .po files from
 // * WithDefaultDomain: name of the default domain. "default", it not specified
+// * WithNamedPlaceholders: use %{name} placeholders instead of fmt.Printf syntax
+// * WithStrictParsing: make AddDomain fail if a catalog is malformed
+// * WithParser: the Parser used to parse catalogs
+// * WithLayout: where to look for catalogs under the source
+// * WithBaseLocale: the Locale consulted for strings without translation
+// * WithLazyDomains: load domains on first use instead of with AddDomain
+//
+// Unless WithParser is specified, the options are also passed to
+// NewParser when loading domains, so any of the options accepted by
+// NewParser may be used as well.
 func NewLocale(l string, options ...Option) Locale {
 	var src Source
 	var defaultDomain string
+	var namedPlaceholders bool
+	var parser *Parser
+	var layout []string
+	var base Locale
+	var lazy bool
 	for _, o := range options {
 		switch o.Name() {
+		case "base_locale":
+			base = o.Value().(Locale)
+		case "lazy_domains":
+			lazy = o.Value().(bool)
+		case "layout":
+			layout = o.Value().([]string)
+		case "parser":
+			parser = o.Value().(*Parser)
 		case "source":
 			src = o.Value().(Source)
 		case "default_domain":
 			defaultDomain = o.Value().(string)
+		case "named_placeholders":
+			namedPlaceholders = o.Value().(bool)
 		}
 	}
 
@@ -71,74 +191,488 @@ func NewLocale(l string, options ...Option) Locale {
 	}
 
 	return &locale{
-		defaultDomain: defaultDomain,
-		domains:       make(map[string]*Po),
-		lang:          l,
-		src:           src,
+		base:              base,
+		defaultDomain:     defaultDomain,
+		domains:           make(map[string]*Po),
+		lang:              l,
+		layout:            layout,
+		lazy:              lazy,
+		namedPlaceholders: namedPlaceholders,
+		normLang:          NormalizeLang(l),
+		options:           options,
+		parser:            parser,
+		src:               src,
 	}
 }
 
-func (l *locale) findPO(dom string) ([]byte, error) {
-	var data []byte
-	var err error
+// defaultLayout is the list of locations where catalogs are looked for,
+// unless WithLayout is specified
+var defaultLayout = []string{
+	"{lang}/LC_MESSAGES/{domain}",
+	"{language}/LC_MESSAGES/{domain}",
+	"{lang}/{domain}",
+	"{language}/{domain}",
+}
 
-	var filenames []string
-	if len(l.lang) > 2 {
-		filenames = make([]string, 0, 4)
-		filenames = append(filenames, filepath.Join(l.lang, "LC_MESSAGES", dom+".po"))
-		filenames = append(filenames, filepath.Join(l.lang[:2], "LC_MESSAGES", dom+".po"))
-		filenames = append(filenames, filepath.Join(l.lang, dom+".po"))
-		filenames = append(filenames, filepath.Join(l.lang[:2], dom+".po"))
-	} else {
-		filenames = make([]string, 0, 2)
-		filenames = append(filenames, filepath.Join(l.lang, "LC_MESSAGES", dom+".po"))
-		filenames = append(filenames, filepath.Join(l.lang, dom+".po"))
+// findCatalog finds the catalog file for the given domain, and returns
+// its content along with its name. The locations in the layout are tried
+// in order. For locations without an extension, the compiled .mo file is
+// preferred over the .po file.
+func (l *locale) findCatalog(dom string) ([]byte, string, error) {
+	langs, languages := localeVariants(l.normLang)
+
+	layout := l.layout
+	if len(layout) == 0 {
+		layout = defaultLayout
 	}
 
-	for _, filename := range filenames {
-		data, err = l.src.ReadFile(filename)
-		if err == nil {
-			return data, nil
+	seen := make(map[string]struct{})
+	for _, tmpl := range layout {
+		tmpl = strings.Replace(tmpl, "{domain}", dom, -1)
+		for _, base := range expandPlaceholder(expandPlaceholder([]string{tmpl}, "{lang}", langs), "{language}", languages) {
+			base = filepath.FromSlash(base)
+
+			var filenames []string
+			switch filepath.Ext(base) {
+			case ".mo", ".po":
+				filenames = []string{base}
+			default:
+				filenames = []string{base + ".mo", base + ".po"}
+			}
+
+			for _, filename := range filenames {
+				if _, ok := seen[filename]; ok {
+					continue
+				}
+				seen[filename] = struct{}{}
+
+				data, err := l.src.ReadFile(filename)
+				if err == nil {
+					return data, filename, nil
+				}
+			}
 		}
 	}
 
-	return nil, errors.Errorf(`locale: could not find file for domain %s in language %s`, dom, l.lang)
+	return nil, "", &missingDomainError{domain: dom, lang: l.lang}
+}
+
+// expandPlaceholder replaces the placeholder in each of the templates
+// with each of the values, in order
+func expandPlaceholder(templates []string, placeholder string, values []string) []string {
+	var list []string
+	for _, tmpl := range templates {
+		if !strings.Contains(tmpl, placeholder) {
+			list = append(list, tmpl)
+			continue
+		}
+		for _, v := range values {
+			list = append(list, strings.Replace(tmpl, placeholder, v, -1))
+		}
+	}
+	return list
+}
+
+func (e *missingDomainError) Error() string {
+	return fmt.Sprintf(`locale: could not find file for domain %s in language %s`, e.domain, e.lang)
+}
+
+// Is makes errors.Is report missing domains as ErrDomainNotFound
+func (e *missingDomainError) Is(target error) bool {
+	return target == ErrDomainNotFound
+}
+
+// isMissingDomain returns true if err was caused by a missing catalog
+func isMissingDomain(err error) bool {
+	_, ok := errors.Cause(err).(*missingDomainError)
+	return ok
+}
+
+// format is used to format strings when there is no Po object to do it
+func (l *locale) format(str string, vars ...interface{}) string {
+	if l.namedPlaceholders {
+		return formatNamed(str, vars...)
+	}
+	return format(str, vars...)
+}
+
+// fprint is used to write formatted strings when there is no Po object
+// to do it
+func (l *locale) fprint(w io.Writer, str string, vars ...interface{}) (int, error) {
+	if l.namedPlaceholders {
+		return io.WriteString(w, formatNamed(str, vars...))
+	}
+	return fprint(w, str, vars...)
+}
+
+// Lang returns the language name of this Locale, exactly as it was
+// passed to NewLocale
+func (l *locale) Lang() string {
+	return l.lang
 }
 
 // AddDomain creates a new domain for a given locale object and initializes the Po object.
 // If the domain exists, it gets reloaded.
+//
+// The new Po object is fully parsed before the lock is taken, and the
+// lock is only held while the domain map is updated. Therefore it is safe
+// to call AddDomain to reload a domain while other goroutines are
+// translating: they will either see the old Po object or the new one,
+// never a partially constructed one.
 func (l *locale) AddDomain(dom string) error {
+	po, err := l.loadDomain(context.Background(), dom)
+	if err != nil {
+		return err
+	}
+
+	// Save new domain
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.domains == nil {
+		l.domains = make(map[string]*Po)
+	}
+	l.domains[dom] = po
+
+	return nil
+}
+
+// AddDomainGlob loads all of the domains whose names match the pattern,
+// using the syntax of filepath.Match (e.g. "billing-*"). The catalogs are
+// looked for using the layout, like in AddDomain, so the Source must be
+// able to list files, i.e. be a GlobSource or a DirSource. Otherwise, an
+// error wrapping ErrGlobNotSupported is returned.
+//
+// All of the matching domains are loaded, even if some of them fail, in
+// which case a MultiError describing every failure is returned. If no
+// domain matches, an error wrapping ErrDomainNotFound is returned.
+func (l *locale) AddDomainGlob(pattern string) error {
+	gs, ok := l.src.(GlobSource)
+	if !ok {
+		ds, ok := l.src.(DirSource)
+		if !ok {
+			return errors.Wrapf(ErrGlobNotSupported, `locale: failed to list domains matching %s`, pattern)
+		}
+		gs = dirGlobSource{ds}
+	}
+
+	domains, err := l.globDomains(gs, pattern)
+	if err != nil {
+		return errors.Wrapf(err, `locale: failed to list domains matching %s`, pattern)
+	}
+	if len(domains) == 0 {
+		return errors.Wrapf(ErrDomainNotFound, `locale: no domain matches %s`, pattern)
+	}
+
+	var errs MultiError
+	for _, dom := range domains {
+		if err := l.AddDomain(dom); err != nil {
+			errs = append(errs, errors.Wrapf(err, `failed to load domain %s`, dom))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// globDomains returns the sorted names of the domains that match the
+// pattern, by listing the catalogs in each of the locations of the
+// layout
+func (l *locale) globDomains(gs GlobSource, pattern string) ([]string, error) {
+	langs, languages := localeVariants(l.normLang)
+
+	layout := l.layout
+	if len(layout) == 0 {
+		layout = defaultLayout
+	}
+
+	found := make(map[string]struct{})
+	for _, tmpl := range layout {
+		for _, base := range expandPlaceholder(expandPlaceholder([]string{tmpl}, "{lang}", langs), "{language}", languages) {
+			// Find the path element that holds the domain name, so that
+			// it can be extracted from the names of the matching files
+			elems := strings.Split(base, "/")
+			idx := -1
+			for i, elem := range elems {
+				if strings.Contains(elem, "{domain}") {
+					idx = i
+					break
+				}
+			}
+			if idx == -1 {
+				continue
+			}
+			prefix := elems[idx][:strings.Index(elems[idx], "{domain}")]
+			suffix := elems[idx][len(prefix)+len("{domain}"):]
+
+			var exts []string
+			switch path.Ext(base) {
+			case ".mo", ".po":
+				exts = []string{""}
+			default:
+				exts = []string{".mo", ".po"}
+			}
+
+			for _, ext := range exts {
+				glob := strings.Replace(base, "{domain}", pattern, 1)
+				matches, err := gs.Glob(filepath.FromSlash(glob + ext))
+				if err != nil {
+					return nil, err
+				}
+
+				for _, m := range matches {
+					elems := strings.Split(filepath.ToSlash(m), "/")
+					if idx >= len(elems) {
+						continue
+					}
+					elem := elems[idx]
+					if idx == len(elems)-1 {
+						elem = strings.TrimSuffix(elem, ext)
+					}
+					if len(elem) < len(prefix)+len(suffix) {
+						continue
+					}
+					found[elem[len(prefix):len(elem)-len(suffix)]] = struct{}{}
+				}
+			}
+		}
+	}
+
+	domains := make([]string, 0, len(found))
+	for dom := range found {
+		domains = append(domains, dom)
+	}
+	sort.Strings(domains)
+	return domains, nil
+}
+
+// loadDomain finds and parses the file for the given domain, without
+// registering it to the Locale. Parsing stops if ctx is canceled
+func (l *locale) loadDomain(ctx context.Context, dom string) (*Po, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Parse file.
-	p := NewParser()
+	p := l.parser
+	if p == nil {
+		p = NewParser(l.options...)
+	}
 
-	data, err := l.findPO(dom)
+	data, filename, err := l.findCatalog(dom)
 	if err != nil {
-		return errors.Wrap(err, `locale: failed to find domain file`)
+		return nil, errors.Wrap(err, `locale: failed to find domain file`)
 	}
 
-	po, err := p.Parse(data)
+	var po *Po
+	if filepath.Ext(filename) == ".mo" {
+		po, err = p.ParseMO(data)
+	} else {
+		po, err = p.ParseContext(ctx, data)
+	}
 	if err != nil {
-		return errors.Wrap(err, `locale: failed to parse file`)
+		return nil, errors.Wrap(err, `locale: failed to parse file`)
+	}
+	po.filename = filename
+	return po, nil
+}
+
+// loadLazily loads the given domain if lazy loading is enabled and
+// the domain has not been loaded yet. The goroutines that request the
+// domain at the same time share a single loading, and its error, if
+// any. A loading that failed is attempted again by the next call.
+func (l *locale) loadLazily(dom string) error {
+	return l.loadLazilyContext(context.Background(), dom)
+}
+
+// loadLazilyContext is like loadLazily, but it stops waiting for the
+// loading if ctx is canceled. The loading itself is shared with the
+// other goroutines, so it is not canceled: it completes in the
+// background. If ctx is canceled before the loading starts, the loading
+// is left to the next call.
+func (l *locale) loadLazilyContext(ctx context.Context, dom string) error {
+	if !l.lazy {
+		return nil
+	}
+
+	v, ok := l.lazyDomains.Load(dom)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ld := &lazyDomain{done: make(chan struct{})}
+		if v, ok = l.lazyDomains.LoadOrStore(dom, ld); !ok {
+			if ctx.Done() == nil {
+				l.loadLazy(dom, ld)
+			} else {
+				go l.loadLazy(dom, ld)
+			}
+		}
+	}
+
+	ld := v.(*lazyDomain)
+	select {
+	case <-ld.done:
+		return ld.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// loadLazy performs the loading of the domain that ld records
+func (l *locale) loadLazy(dom string, ld *lazyDomain) {
+	defer close(ld.done)
+
+	if l.HasDomain(dom) {
+		return
+	}
+
+	po, err := l.loadDomain(context.Background(), dom)
+	if err != nil {
+		ld.err = err
+		l.lazyDomains.CompareAndDelete(dom, ld)
+		return
 	}
 
-	// Save new domain
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// RemoveDomain may have been called in the meantime, in which
+	// case the next call loads the domain again
+	if v, ok := l.lazyDomains.Load(dom); !ok || v != ld {
+		return
+	}
+	// AddDomain may have been called in the meantime
+	if _, ok := l.domains[dom]; !ok {
+		l.domains[dom] = po
+	}
+}
+
+// replaceDomains swaps in all of the given Po objects at once
+func (l *locale) replaceDomains(domains map[string]*Po) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	if l.domains == nil {
 		l.domains = make(map[string]*Po)
 	}
-	l.domains[dom] = po
+	for dom, po := range domains {
+		l.domains[dom] = po
+	}
+}
+
+// RemoveDomain removes the given domain from this Locale, so that the
+// associated Po object can be garbage collected. It is a no-op if the
+// domain has not been loaded. With WithLazyDomains, the domain is loaded
+// again by the next lookup.
+func (l *locale) RemoveDomain(dom string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.domains, dom)
+	l.lazyDomains.Delete(dom)
+}
+
+// Domains returns the sorted list of domain names that have been
+// loaded into this Locale
+func (l *locale) Domains() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	list := make([]string, 0, len(l.domains))
+	for dom := range l.domains {
+		list = append(list, dom)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// HasDomain returns true if the given domain has been loaded into
+// this Locale
+func (l *locale) HasDomain(dom string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	_, ok := l.domains[dom]
+	return ok
+}
+
+// Domain returns the given domain of this Locale, or the default domain
+// if the name is empty. The second return value is false if the domain
+// has not been loaded.
+//
+// The Domain object refers to the Po object that was loaded at the time
+// Domain was called: if the domain is reloaded afterwards, call Domain
+// again to use the new translations.
+func (l *locale) Domain(dom string) (*Domain, bool) {
+	dom = l.resolveDomain(dom)
+	l.loadLazily(dom)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	po, ok := l.domains[dom]
+	if !ok || po == nil {
+		return nil, false
+	}
+	return &Domain{name: dom, po: po}, true
+}
+
+// DomainSource returns the name of the file that was loaded for the
+// given domain, relative to the root of the Source (for example
+// "en/LC_MESSAGES/default.po"). An empty name stands for the default
+// domain. It returns an empty string if the domain has not been loaded.
+func (l *locale) DomainSource(dom string) string {
+	dom = l.resolveDomain(dom)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	po, ok := l.domains[dom]
+	if !ok || po == nil {
+		return ""
+	}
+	return po.filename
+}
+
+// SetDefaultDomain changes the domain that is used by Get, GetN, GetC
+// and GetNC. The domain must already be loaded, otherwise an error is
+// returned and the default domain is not changed.
+func (l *locale) SetDefaultDomain(dom string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
+	if _, ok := l.domains[dom]; !ok {
+		return errors.Errorf(`locale: domain %s is not loaded`, dom)
+	}
+	l.defaultDomain = dom
 	return nil
 }
 
+// getDefaultDomain returns the name of the default domain
+func (l *locale) getDefaultDomain() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.defaultDomain
+}
+
+// resolveDomain returns the name of the domain to use for dom: an empty
+// name stands for the default domain, as with dgettext(3)
+func (l *locale) resolveDomain(dom string) string {
+	if dom == "" {
+		return l.getDefaultDomain()
+	}
+	return dom
+}
+
 // Get uses the default domain to return the corresponding translation of a
 // given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the
 // formatted string using the fmt.Printf syntax.
 func (l *locale) Get(str string, vars ...interface{}) string {
-	return l.GetD(l.defaultDomain, str, vars...)
+	return l.GetD(l.getDefaultDomain(), str, vars...)
 }
 
 // GetN retrieves the (N)th plural form of translation for the given string in
@@ -146,46 +680,152 @@ func (l *locale) Get(str string, vars ...interface{}) string {
 // Supports optional parameters (vars... interface{}) to be inserted on the
 // formatted string using the fmt.Printf syntax.
 func (l *locale) GetN(str, plural string, n int, vars ...interface{}) string {
-	return l.GetND(l.defaultDomain, str, plural, n, vars...)
+	return l.GetND(l.getDefaultDomain(), str, plural, n, vars...)
 }
 
 // GetD returns the corresponding translation in the given domain for the given string.
+// If the domain has no translation for the string, or if the domain has
+// not been loaded, the formatted string is returned. Use TryGetD to tell
+// whether a translation was found, and GetDErr to tell why not.
+// As with dgettext(3), an empty domain name stands for the default
+// domain. This also holds for the other methods taking a domain name.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (l *locale) GetD(dom, str string, vars ...interface{}) string {
 	return l.GetND(dom, str, str, 1, vars...)
 }
 
+// GetContext is like Get, but honors the cancellation of ctx. With
+// WithLazyDomains, if the default domain has to be loaded and ctx is
+// canceled before the loading completes, the string is returned as if
+// the domain had no translation for it. The loading is shared with the
+// other lookups, so it is not canceled, and the domain is used by the
+// lookups that follow once it is loaded.
+func (l *locale) GetContext(ctx context.Context, str string, vars ...interface{}) string {
+	return l.GetDContext(ctx, l.getDefaultDomain(), str, vars...)
+}
+
+// GetDContext is like GetD, but honors the cancellation of ctx, like
+// GetContext.
+func (l *locale) GetDContext(ctx context.Context, dom, str string, vars ...interface{}) string {
+	dom = l.resolveDomain(dom)
+	l.loadLazilyContext(ctx, dom)
+	return l.getND(dom, str, str, 1, vars...)
+}
+
+// GetDErr is like GetD, but also returns an error if the domain is not
+// available. There are three cases:
+//
+//   - the domain is loaded and has a translation for the string: the
+//     translation is returned, with a nil error
+//   - the domain is loaded, but has no translation for the string: the
+//     string is returned, with a nil error
+//   - the domain is not loaded: the string is returned (or the
+//     translation from the base locale, if any), with an error
+//
+// If the domain was not loaded because AddDomain was never called for
+// it, the error wraps ErrDomainNotLoaded. With WithLazyDomains, the
+// error is the one that occurred when loading the domain, which wraps
+// ErrDomainNotFound if there is no catalog for the domain.
+func (l *locale) GetDErr(dom, str string, vars ...interface{}) (string, error) {
+	dom = l.resolveDomain(dom)
+	err := l.loadLazily(dom)
+	if err == nil && !l.HasDomain(dom) {
+		err = errors.Wrapf(ErrDomainNotLoaded, `locale: failed to get domain %s`, dom)
+	}
+	if err != nil && l.HasDomain(dom) {
+		// The domain has been added since the lazy loading failed
+		err = nil
+	}
+	return l.GetD(dom, str, vars...), err
+}
+
+// GetRaw uses the default domain to return the translation of the given
+// string exactly as it appears in the catalog, without formatting it.
+// See Po.GetRaw.
+func (l *locale) GetRaw(str string) string {
+	return l.GetRawD(l.getDefaultDomain(), str)
+}
+
+// GetRawD is like GetRaw, but uses the given domain.
+func (l *locale) GetRawD(dom, str string) string {
+	dom = l.resolveDomain(dom)
+	l.loadLazily(dom)
+
+	po, base := l.lookupDomain(dom)
+	if base != nil && (po == nil || !po.hasTranslation(str, 1)) {
+		return getRawD(base, dom, str)
+	}
+	if po == nil {
+		return str
+	}
+
+	return po.GetRaw(str)
+}
+
+// TryGetD is like GetD, but the second return value reports whether a
+// translation was found. It is false if the domain has not been loaded,
+// or if the domain does not contain the given string.
+func (l *locale) TryGetD(dom, str string, vars ...interface{}) (string, bool) {
+	dom = l.resolveDomain(dom)
+	l.loadLazily(dom)
+
+	po, base := l.lookupDomain(dom)
+	if po != nil {
+		if s, found := po.TryGet(str, vars...); found || base == nil {
+			return s, found
+		}
+	}
+	if base != nil {
+		return tryGetD(base, dom, str, vars...)
+	}
+	return l.format(str, vars...), false
+}
+
 // GetND retrieves the (N)th plural form of translation in the given domain for the given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (l *locale) GetND(dom, str, plural string, n int, vars ...interface{}) string {
-	// Sync read
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+	dom = l.resolveDomain(dom)
+	l.loadLazily(dom)
+	return l.getND(dom, str, plural, n, vars...)
+}
 
-	if l.domains == nil {
-		return format(plural, vars...)
+// getND implements GetND, once the domain has been loaded if necessary
+func (l *locale) getND(dom, str, plural string, n int, vars ...interface{}) string {
+	po, base := l.lookupDomain(dom)
+	if base != nil && (po == nil || !po.hasTranslation(str, n)) {
+		return base.GetND(dom, str, plural, n, vars...)
 	}
-
-	po, ok := l.domains[dom]
-	if !ok || po == nil {
-		return format(plural, vars...)
+	if po == nil {
+		return l.format(plural, vars...)
 	}
 
 	return po.GetN(str, plural, n, vars...)
 }
 
+// lookupDomain returns the catalog of the given domain (nil if it has
+// not been loaded) and the base locale. The base locale must only be
+// used once the lock has been released, as it may be a locale that
+// refers to this one
+func (l *locale) lookupDomain(dom string) (*Po, Locale) {
+	// Sync read
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.domains[dom], l.base
+}
+
 // GetC uses the default domain to return the corresponding translation of
 // the given string in the given context.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (l *locale) GetC(str, ctx string, vars ...interface{}) string {
-	return l.GetDC(l.defaultDomain, str, ctx, vars...)
+	return l.GetDC(l.getDefaultDomain(), str, ctx, vars...)
 }
 
 // GetNC retrieves the (N)th plural form of translation for the given string
 // in the given context in the default domain.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (l *locale) GetNC(str, plural string, n int, ctx string, vars ...interface{}) string {
-	return l.GetNDC(l.defaultDomain, str, plural, n, ctx, vars...)
+	return l.GetNDC(l.getDefaultDomain(), str, plural, n, ctx, vars...)
 }
 
 // GetDC returns the corresponding translation in the given domain for the given string in the given context.
@@ -197,18 +837,153 @@ func (l *locale) GetDC(dom, str, ctx string, vars ...interface{}) string {
 // GetNDC retrieves the (N)th plural form of translation in the given domain for the given string in the given context.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (l *locale) GetNDC(dom, str, plural string, n int, ctx string, vars ...interface{}) string {
-	// Sync read
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+	dom = l.resolveDomain(dom)
+	l.loadLazily(dom)
 
-	if l.domains == nil {
-		return format(plural, vars)
+	po, base := l.lookupDomain(dom)
+	if base != nil && (po == nil || !po.hasTranslationC(str, ctx, n)) {
+		return base.GetNDC(dom, str, plural, n, ctx, vars...)
 	}
-
-	po, ok := l.domains[dom]
-	if !ok || po == nil {
-		return format(plural, vars)
+	if po == nil {
+		return l.format(plural, vars...)
 	}
 
 	return po.GetNC(str, plural, n, ctx, vars...)
 }
+
+// Fprintf writes the translation of the given string in the default
+// domain to w, formatted with the given values. The output is the same
+// as that of Get, but it is written directly to w when possible.
+// It returns the number of bytes written and any write error.
+func (l *locale) Fprintf(w io.Writer, str string, vars ...interface{}) (int, error) {
+	return l.FprintfD(w, l.getDefaultDomain(), str, vars...)
+}
+
+// FprintfD is like Fprintf, but uses the given domain, like GetD.
+func (l *locale) FprintfD(w io.Writer, dom, str string, vars ...interface{}) (int, error) {
+	dom = l.resolveDomain(dom)
+	l.loadLazily(dom)
+
+	po, base := l.lookupDomain(dom)
+	if base != nil && (po == nil || !po.hasTranslation(str, 1)) {
+		return fprintfD(w, base, dom, str, vars...)
+	}
+	if po == nil {
+		return l.fprint(w, str, vars...)
+	}
+
+	return po.Fprintf(w, str, vars...)
+}
+
+// FprintfC is like Fprintf, but uses the given context, like GetC.
+func (l *locale) FprintfC(w io.Writer, str, ctx string, vars ...interface{}) (int, error) {
+	return l.FprintfDC(w, l.getDefaultDomain(), str, ctx, vars...)
+}
+
+// FprintfDC is like Fprintf, but uses the given domain and context,
+// like GetDC.
+func (l *locale) FprintfDC(w io.Writer, dom, str, ctx string, vars ...interface{}) (int, error) {
+	dom = l.resolveDomain(dom)
+	l.loadLazily(dom)
+
+	po, base := l.lookupDomain(dom)
+	if base != nil && (po == nil || !po.hasTranslationC(str, ctx, 1)) {
+		return fprintfDC(w, base, dom, str, ctx, vars...)
+	}
+	if po == nil {
+		return l.fprint(w, str, vars...)
+	}
+
+	return po.FprintfC(w, str, ctx, vars...)
+}
+
+// The following functions call the methods of the optional interfaces
+// of Locale on l, and emulate them with the methods of Locale when l
+// does not implement the interface.
+
+// getRaw calls l.GetRaw, or l.Get without values
+func getRaw(l Locale, str string) string {
+	if ll, ok := l.(LookupLocale); ok {
+		return ll.GetRaw(str)
+	}
+	return l.Get(str)
+}
+
+// getRawD calls l.GetRawD, or l.GetD without values
+func getRawD(l Locale, dom, str string) string {
+	if ll, ok := l.(LookupLocale); ok {
+		return ll.GetRawD(dom, str)
+	}
+	return l.GetD(dom, str)
+}
+
+// tryGetD calls l.TryGetD. Otherwise, it calls l.GetD, and reports the
+// translation as found if it differs from the source string
+func tryGetD(l Locale, dom, str string, vars ...interface{}) (string, bool) {
+	if ll, ok := l.(LookupLocale); ok {
+		return ll.TryGetD(dom, str, vars...)
+	}
+
+	s := l.GetD(dom, str, vars...)
+	src := str
+	if len(vars) > 0 {
+		src = fmt.Sprintf(str, vars...)
+	}
+	return s, s != src
+}
+
+// getDErr calls l.GetDErr, or l.GetD, which never fails
+func getDErr(l Locale, dom, str string, vars ...interface{}) (string, error) {
+	if ll, ok := l.(LookupLocale); ok {
+		return ll.GetDErr(dom, str, vars...)
+	}
+	return l.GetD(dom, str, vars...), nil
+}
+
+// getContext calls l.GetContext, or l.Get
+func getContext(ctx context.Context, l Locale, str string, vars ...interface{}) string {
+	if cl, ok := l.(ContextLocale); ok {
+		return cl.GetContext(ctx, str, vars...)
+	}
+	return l.Get(str, vars...)
+}
+
+// getDContext calls l.GetDContext, or l.GetD
+func getDContext(ctx context.Context, l Locale, dom, str string, vars ...interface{}) string {
+	if cl, ok := l.(ContextLocale); ok {
+		return cl.GetDContext(ctx, dom, str, vars...)
+	}
+	return l.GetD(dom, str, vars...)
+}
+
+// fprintf calls l.Fprintf, or writes the result of l.Get to w
+func fprintf(w io.Writer, l Locale, str string, vars ...interface{}) (int, error) {
+	if pl, ok := l.(PrinterLocale); ok {
+		return pl.Fprintf(w, str, vars...)
+	}
+	return io.WriteString(w, l.Get(str, vars...))
+}
+
+// fprintfD calls l.FprintfD, or writes the result of l.GetD to w
+func fprintfD(w io.Writer, l Locale, dom, str string, vars ...interface{}) (int, error) {
+	if pl, ok := l.(PrinterLocale); ok {
+		return pl.FprintfD(w, dom, str, vars...)
+	}
+	return io.WriteString(w, l.GetD(dom, str, vars...))
+}
+
+// fprintfC calls l.FprintfC, or writes the result of l.GetC to w
+func fprintfC(w io.Writer, l Locale, str, ctx string, vars ...interface{}) (int, error) {
+	if pl, ok := l.(PrinterLocale); ok {
+		return pl.FprintfC(w, str, ctx, vars...)
+	}
+	return io.WriteString(w, l.GetC(str, ctx, vars...))
+}
+
+// fprintfDC calls l.FprintfDC, or writes the result of l.GetDC to w
+func fprintfDC(w io.Writer, l Locale, dom, str, ctx string, vars ...interface{}) (int, error) {
+	if pl, ok := l.(PrinterLocale); ok {
+		return pl.FprintfDC(w, dom, str, ctx, vars...)
+	}
+	return io.WriteString(w, l.GetDC(dom, str, ctx, vars...))
+}
@@ -1,32 +1,40 @@
 package gettext
 
 import (
+	"context"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
+	"golang.org/x/text/language"
 )
 
 // LocaleSet is a convenience wrapper around Locale objects. Multiple
 // locales can be stored in this set, and users may dynamically ask for
 // a Locale object for a given locale name.
 type LocaleSet struct {
-	domains map[string]struct{}
-	locales map[string]Locale
-	mu      sync.RWMutex
-	options []Option
+	domains  map[string]struct{}
+	locales  map[string]Locale
+	missing  map[string][]string // domains skipped by AddLocale, by locale
+	fallback string              // name of the locale returned on a miss
+	mu       sync.RWMutex
+	options  []Option
 }
 
 func NewLocaleSet() *LocaleSet {
 	return &LocaleSet{
 		domains: make(map[string]struct{}),
 		locales: make(map[string]Locale),
+		missing: make(map[string][]string),
 	}
 }
 
 // GetLocale returns the Locale corresponding to the ID l (i.e. "en", "ja",
 // etc). If the corresponding locale is not found, an error is returned, and
-// the first return value is set to *NullLocale, which you can use as a
-// default fallback
+// the first return value is set to the fallback locale (see
+// SetFallbackLocale), or to *NullLocale if there is none, which you can
+// use as a default fallback
 func (s *LocaleSet) GetLocale(l string) (Locale, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -34,11 +42,161 @@ func (s *LocaleSet) GetLocale(l string) (Locale, error) {
 		return locale, nil
 	}
 
-	return &NullLocale{}, errors.New(`locale not found`)
+	_, locale := s.fallbackLocale()
+	return locale, errors.Wrapf(ErrLocaleNotFound, `failed to get locale %s`, l)
+}
+
+// SetFallbackLocale sets the name of the locale that is used when the
+// requested locale is not available, instead of a NullLocale. This is
+// especially useful with catalogs keyed by symbolic msgids, for which
+// a NullLocale returns the keys. The fallback is looked up when it is
+// needed, so it may be added to the set later. Pass an empty string to
+// remove the fallback.
+func (s *LocaleSet) SetFallbackLocale(l string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fallback = l
+}
+
+// fallbackLocale returns the fallback locale and its name, or a
+// NullLocale and an empty string. The caller must hold the lock
+func (s *LocaleSet) fallbackLocale() (string, Locale) {
+	if locale, ok := s.locales[s.fallback]; ok && s.fallback != "" {
+		return s.fallback, locale
+	}
+	return "", &NullLocale{}
+}
+
+// Translator returns a Translator for the first of the given languages
+// that is available in this set. Each language is first looked up by
+// its exact name, then by its normalized name (see NormalizeLang), and
+// finally by its language code alone, so "en-US" matches a locale
+// named "en" if there is no "en_US".
+//
+// If none of the languages are available, the Translator uses the
+// fallback locale (see SetFallbackLocale). If there is none either, it
+// uses a NullLocale, and its Lang method returns an empty string.
+func (s *LocaleSet) Translator(langs ...string) *Translator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, lang := range langs {
+		if name, locale, ok := s.findLocale(lang); ok {
+			return &Translator{lang: name, locale: locale}
+		}
+	}
+
+	name, locale := s.fallbackLocale()
+	return &Translator{lang: name, locale: locale}
+}
+
+// Match returns the locale that best matches the value of an HTTP
+// Accept-Language header, along with the name it was added to the set
+// with. The languages are matched with golang.org/x/text/language, which
+// takes their quality values into account as well as the distance
+// between scripts and regions (e.g. "en-GB" matches a locale named "en").
+// Languages with a quality of 0 and the wildcard "*" never match.
+//
+// If nothing matches, or if the header is malformed, the fallback locale
+// and its name are returned, or a NullLocale and an empty string if there
+// is no fallback.
+func (s *LocaleSet) Match(acceptLanguage string) (Locale, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags, qs, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err == nil {
+		var desired []language.Tag
+		for i, tag := range tags {
+			if qs[i] > 0 && tag != language.Und {
+				desired = append(desired, tag)
+			}
+		}
+
+		var names []string
+		var supported []language.Tag
+		for _, name := range s.sortedLocales() {
+			if tag := languageTag(name); tag != language.Und {
+				names = append(names, name)
+				supported = append(supported, tag)
+			}
+		}
+
+		if len(desired) > 0 && len(supported) > 0 {
+			_, i, c := language.NewMatcher(supported).Match(desired...)
+			if c != language.No {
+				return s.locales[names[i]], names[i]
+			}
+		}
+	}
+
+	name, locale := s.fallbackLocale()
+	return locale, name
+}
+
+// findLocale looks up the locale for the given language, falling back
+// to the normalized name and then to the language code. The caller
+// must hold the lock
+func (s *LocaleSet) findLocale(lang string) (string, Locale, bool) {
+	if locale, ok := s.locales[lang]; ok {
+		return lang, locale, true
+	}
+
+	norm := NormalizeLang(stripLocaleSuffix(lang))
+	if norm == "" {
+		return "", nil, false
+	}
+
+	names := s.sortedLocales()
+
+	candidates := []string{norm}
+	if i := strings.IndexByte(norm, '_'); i > -1 {
+		candidates = append(candidates, norm[:i])
+	}
+
+	for _, candidate := range candidates {
+		for _, name := range names {
+			if NormalizeLang(stripLocaleSuffix(name)) == candidate {
+				return name, s.locales[name], true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// Locales returns the sorted list of locale names that have been added
+// to this set
+func (s *LocaleSet) Locales() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sortedLocales()
+}
+
+// sortedLocales returns the sorted list of locale names. The caller must
+// hold the lock
+func (s *LocaleSet) sortedLocales() []string {
+	list := make([]string, 0, len(s.locales))
+	for l := range s.locales {
+		list = append(list, l)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// HasLocale returns true if the given locale has been added to this set
+func (s *LocaleSet) HasLocale(l string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.locales[l]
+	return ok
 }
 
 // Sets the options that are passed to `NewLocale()` when creating
-// a new locale
+// a new locale. WithIgnoreMissingDomains is also recognized, and
+// changes the behavior of AddLocale.
 func (s *LocaleSet) Options(options ...Option) {
 	s.options = options
 }
@@ -51,6 +209,20 @@ func (s *LocaleSet) AddDomain(domain string) error {
 	return nil
 }
 
+// RemoveDomain removes the domain from the set, as well as from all of
+// the locales that have been added to it
+func (s *LocaleSet) RemoveDomain(domain string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.domains, domain)
+	for _, locale := range s.locales {
+		if dl, ok := locale.(DomainLocale); ok {
+			dl.RemoveDomain(domain)
+		}
+	}
+}
+
 func (s *LocaleSet) SetLocale(l string, locale Locale) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -59,22 +231,183 @@ func (s *LocaleSet) SetLocale(l string, locale Locale) error {
 	return nil
 }
 
+// AddLocale creates a new Locale for l, and loads all of the domains
+// of the set into it. If any of the domains fail to load, the locale is
+// not added, and a MultiError describing every failure is returned.
 func (s *LocaleSet) AddLocale(l string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.addLocale(l, s.options, nil)
+}
+
+// AddLocaleWithOptions is like AddLocale, but the given options are
+// passed to NewLocale in addition to those set with Options, and take
+// precedence over them. This allows using e.g. a different default
+// domain (WithDefaultDomain) or Source (WithSource) for a single locale,
+// without changing the options of the other locales.
+func (s *LocaleSet) AddLocaleWithOptions(l string, options ...Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	merged := make([]Option, 0, len(s.options)+len(options))
+	merged = append(merged, s.options...)
+	merged = append(merged, options...)
+	return s.addLocale(l, merged, nil)
+}
+
+// AddLocaleWithProgress is like AddLocale, but calls progress after each
+// domain has been loaded, with the error that occurred if any, so that
+// the progress of long loads can be reported. Unlike AddLocale, the
+// locale is added even if some of the domains fail to load, with the
+// domains that could be loaded, and the failures are returned as a
+// MultiError. Domains skipped because of WithIgnoreMissingDomains are
+// reported to progress with their error, but are not returned.
+//
+// progress is called with the lock of the set held, so it must not call
+// the methods of the set.
+func (s *LocaleSet) AddLocaleWithProgress(l string, progress func(domain string, err error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addLocale(l, s.options, progress)
+}
+
+// addLocale implements AddLocale, creating the locale with the given
+// options. If progress is not nil, it is called for each domain, and the
+// locale is added even if some domains fail. The caller must hold the
+// lock
+func (s *LocaleSet) addLocale(l string, options []Option, progress func(string, error)) error {
 	if _, ok := s.locales[l]; ok {
 		return nil
 	}
 
-	locale := NewLocale(l, s.options...)
+	var ignoreMissing bool
+	for _, o := range options {
+		if o.Name() == "ignore_missing_domains" {
+			ignoreMissing = o.Value().(bool)
+		}
+	}
 
+	locale := NewLocale(l, options...)
+
+	domains := make([]string, 0, len(s.domains))
 	for domain := range s.domains {
-		if err := locale.AddDomain(domain); err != nil {
-			return errors.Wrapf(err, `failed to load domain %s for locale %s`, domain, l)
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	// Every domain is attempted, so that all of the problems can be
+	// reported at once
+	var missing []string
+	var errs MultiError
+	for _, domain := range domains {
+		err := locale.AddDomain(domain)
+		if progress != nil {
+			progress(domain, err)
 		}
+		if err != nil {
+			if ignoreMissing && isMissingDomain(err) {
+				missing = append(missing, domain)
+				continue
+			}
+			errs = append(errs, errors.Wrapf(err, `failed to load domain %s for locale %s`, domain, l))
+		}
+	}
+	// With a progress callback, the caller chose to go on with the
+	// domains that could be loaded
+	if len(errs) > 0 && progress == nil {
+		return errs
 	}
 
 	s.locales[l] = locale
+	if len(missing) > 0 {
+		s.missing[l] = missing
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// MissingDomains returns the sorted list of domains that were skipped
+// when the given locale was added, because there was no catalog for
+// them. See WithIgnoreMissingDomains.
+func (s *LocaleSet) MissingDomains(l string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]string(nil), s.missing[l]...)
+}
+
+// RemoveLocale removes the locale from the set. It is a no-op if the
+// locale has not been added.
+func (s *LocaleSet) RemoveLocale(l string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.locales, l)
+	delete(s.missing, l)
+}
+
+// Reload re-reads the files for every domain of every locale in the set
+// from their sources.
+//
+// All of the files are parsed before any of the locales are updated. If
+// any of them fail to load, a MultiError describing all of the failures
+// is returned, and the set is left untouched. Locales that were
+// registered through SetLocale are not reloaded unless they were created
+// by NewLocale.
+//
+// The locales are then updated while the set is locked, so the methods
+// of the set (GetLocale, Translator, Match...) never observe a partial
+// reload. However, each locale is updated in turn: a goroutine that is
+// translating with locales that it obtained earlier may see some of them
+// reloaded and others not, until Reload returns.
+func (s *LocaleSet) Reload() error {
+	s.mu.RLock()
+	locales := make(map[string]*locale, len(s.locales))
+	for name, l := range s.locales {
+		if loc, ok := l.(*locale); ok {
+			locales[name] = loc
+		}
+	}
+	s.mu.RUnlock()
+
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs MultiError
+	loaded := make(map[*locale]map[string]*Po, len(locales))
+	for _, name := range names {
+		loc := locales[name]
+		pos := make(map[string]*Po)
+		for _, domain := range loc.Domains() {
+			po, err := loc.loadDomain(context.Background(), domain)
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, `failed to reload domain %s for locale %s`, domain, name))
+				continue
+			}
+			pos[domain] = po
+		}
+		loaded[loc] = pos
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		// Skip the locales that were removed or replaced in the meantime
+		if loc := locales[name]; s.locales[name] == loc {
+			loc.replaceDomains(loaded[loc])
+		}
+	}
 	return nil
 }
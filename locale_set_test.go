@@ -0,0 +1,536 @@
+package gettext
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleSetLocales(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hello!"
+`),
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	s.AddDomain("default")
+
+	assert.Empty(t, s.Locales(), `Locales should be empty`)
+	assert.False(t, s.HasLocale("ja"), `HasLocale(ja) should be false`)
+
+	for _, l := range []string{"ja", "en"} {
+		if !assert.NoError(t, s.AddLocale(l), `AddLocale should succeed`) {
+			return
+		}
+	}
+
+	assert.Equal(t, []string{"en", "ja"}, s.Locales())
+	assert.True(t, s.HasLocale("ja"), `HasLocale(ja) should be true`)
+	assert.False(t, s.HasLocale("fr"), `HasLocale(fr) should be false`)
+
+	l, err := s.GetLocale("ja")
+	if assert.NoError(t, err, `GetLocale should succeed`) {
+		assert.Equal(t, "こんにちは", l.Get("Hello"))
+	}
+}
+
+func TestLocaleSetRemove(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hello!"
+`),
+		"en/LC_MESSAGES/extra.po": []byte(`
+msgid "Extra"
+msgstr "Extra!"
+`),
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+		"ja/LC_MESSAGES/extra.po": []byte(`
+msgid "Extra"
+msgstr "おまけ"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	s.AddDomain("default")
+	s.AddDomain("extra")
+
+	for _, l := range []string{"en", "ja"} {
+		if !assert.NoError(t, s.AddLocale(l), `AddLocale should succeed`) {
+			return
+		}
+	}
+
+	s.RemoveLocale("en")
+	assert.Equal(t, []string{"ja"}, s.Locales())
+	_, err := s.GetLocale("en")
+	assert.Error(t, err, `GetLocale(en) should fail`)
+	assert.True(t, errors.Is(err, ErrLocaleNotFound), `error should be ErrLocaleNotFound`)
+
+	l, err := s.GetLocale("ja")
+	if !assert.NoError(t, err, `GetLocale(ja) should succeed`) {
+		return
+	}
+	assert.Equal(t, []string{"default", "extra"}, l.(DomainLocale).Domains())
+
+	s.RemoveDomain("extra")
+	assert.Equal(t, []string{"default"}, l.(DomainLocale).Domains())
+	assert.Equal(t, "Extra", l.GetD("extra", "Extra"))
+
+	// Locales added afterwards should not load the removed domain
+	if !assert.NoError(t, s.AddLocale("en"), `AddLocale should succeed`) {
+		return
+	}
+	l, _ = s.GetLocale("en")
+	assert.Equal(t, []string{"default"}, l.(DomainLocale).Domains())
+}
+
+func TestLocaleSetReload(t *testing.T) {
+	files := map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hello!"
+`),
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+	}
+
+	s := NewLocaleSet()
+	s.Options(WithSource(NewMapSource(files)))
+	s.AddDomain("default")
+
+	for _, l := range []string{"en", "ja"} {
+		if !assert.NoError(t, s.AddLocale(l), `AddLocale should succeed`) {
+			return
+		}
+	}
+
+	en, _ := s.GetLocale("en")
+	ja, _ := s.GetLocale("ja")
+
+	files["en/LC_MESSAGES/default.po"] = []byte(`
+msgid "Hello"
+msgstr "Hello, updated!"
+`)
+	files["ja/LC_MESSAGES/default.po"] = []byte(`
+msgid "Hello"
+msgstr "こんにちは (更新)"
+`)
+
+	if !assert.NoError(t, s.Reload(), `Reload should succeed`) {
+		return
+	}
+	assert.Equal(t, "Hello, updated!", en.Get("Hello"))
+	assert.Equal(t, "こんにちは (更新)", ja.Get("Hello"))
+
+	// If any file fails to load, nothing should be updated
+	files["en/LC_MESSAGES/default.po"] = []byte(`
+msgid "Hello"
+msgstr "Hello, again!"
+`)
+	delete(files, "ja/LC_MESSAGES/default.po")
+
+	err := s.Reload()
+	if !assert.Error(t, err, `Reload should fail`) {
+		return
+	}
+	assert.Contains(t, err.Error(), "locale ja")
+	assert.Equal(t, "Hello, updated!", en.Get("Hello"))
+	assert.Equal(t, "こんにちは (更新)", ja.Get("Hello"))
+}
+
+func TestLocaleSetTranslator(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hi!"
+`),
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+		"pt_BR/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Olá"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	s.AddDomain("default")
+	for _, l := range []string{"en", "ja", "pt_BR"} {
+		if !assert.NoError(t, s.AddLocale(l), `AddLocale should succeed`) {
+			return
+		}
+	}
+
+	tests := []struct {
+		langs    []string
+		lang     string
+		expected string
+	}{
+		{langs: []string{"ja"}, lang: "ja", expected: "こんにちは"},
+		{langs: []string{"fr", "ja", "en"}, lang: "ja", expected: "こんにちは"},
+		{langs: []string{"pt-br"}, lang: "pt_BR", expected: "Olá"},
+		{langs: []string{"en-US"}, lang: "en", expected: "Hi!"},
+		{langs: []string{"ja_JP.UTF-8"}, lang: "ja", expected: "こんにちは"},
+		{langs: []string{"fr", "de"}, lang: "", expected: "Hello"},
+		{langs: nil, lang: "", expected: "Hello"},
+	}
+
+	for _, test := range tests {
+		tr := s.Translator(test.langs...)
+		assert.Equal(t, test.lang, tr.Lang(), `Lang should match`)
+		assert.Equal(t, test.expected, tr.Get("Hello"), `Get should match`)
+	}
+}
+
+func TestTranslatorContext(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	s.AddDomain("default")
+	if !assert.NoError(t, s.AddLocale("ja"), `AddLocale should succeed`) {
+		return
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, "Hello", TranslatorFromContext(ctx).Get("Hello"), `missing Translator should not translate`)
+
+	ctx = NewTranslatorContext(ctx, s.Translator("ja"))
+	assert.Equal(t, "こんにちは", TranslatorFromContext(ctx).Get("Hello"), `Translator should be retrieved from context`)
+}
+
+func TestLocaleSetMatch(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hi!"
+`),
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	s.AddDomain("default")
+	for _, l := range []string{"en", "ja"} {
+		if !assert.NoError(t, s.AddLocale(l), `AddLocale should succeed`) {
+			return
+		}
+	}
+
+	l, tag := s.Match("fr-CH, fr;q=0.9, ja-JP;q=0.8, en;q=0.7")
+	assert.Equal(t, "ja", tag, `ja should be matched`)
+	assert.Equal(t, "こんにちは", l.Get("Hello"))
+
+	l, tag = s.Match("en-GB;q=0.5, ja;q=0")
+	assert.Equal(t, "en", tag, `en should be matched`)
+	assert.Equal(t, "Hi!", l.Get("Hello"))
+
+	l, tag = s.Match("de, *")
+	assert.Equal(t, "", tag, `nothing should be matched`)
+	assert.IsType(t, &NullLocale{}, l, `NullLocale should be returned`)
+
+	_, tag = s.Match("ja;q=0, en-US")
+	assert.Equal(t, "en", tag, `en-US should match en`)
+
+	_, tag = s.Match("ja;q=0")
+	assert.Equal(t, "", tag, `languages with a quality of 0 should not be matched`)
+
+	l, tag = s.Match("en;q=bogus")
+	assert.Equal(t, "", tag, `malformed header should not be matched`)
+	assert.IsType(t, &NullLocale{}, l, `NullLocale should be returned`)
+}
+
+func TestLocaleSetIgnoreMissingDomains(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hi!"
+`),
+		"en/LC_MESSAGES/extra.po": []byte(`
+msgid "Extra"
+msgstr "Extra!"
+`),
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour"
+bogus
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	s.AddDomain("default")
+	s.AddDomain("extra")
+	assert.Error(t, s.AddLocale("ja"), `AddLocale should fail by default`)
+
+	s = NewLocaleSet()
+	s.Options(WithSource(src), WithIgnoreMissingDomains(true), WithStrictParsing(true))
+	s.AddDomain("default")
+	s.AddDomain("extra")
+
+	for _, l := range []string{"en", "ja"} {
+		if !assert.NoError(t, s.AddLocale(l), `AddLocale should succeed`) {
+			return
+		}
+	}
+	assert.Empty(t, s.MissingDomains("en"), `no domains should be missing for en`)
+	assert.Equal(t, []string{"extra"}, s.MissingDomains("ja"))
+
+	l, _ := s.GetLocale("ja")
+	assert.Equal(t, "こんにちは", l.Get("Hello"))
+	assert.Equal(t, "Extra", l.GetD("extra", "Extra"), `missing domain should not translate`)
+
+	assert.Error(t, s.AddLocale("fr"), `parse errors should not be ignored`)
+
+	s.RemoveLocale("ja")
+	assert.Empty(t, s.MissingDomains("ja"), `removed locales should be forgotten`)
+}
+
+func TestLocaleSetAddLocaleErrors(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	for _, dom := range []string{"alpha", "default", "zulu"} {
+		s.AddDomain(dom)
+	}
+
+	err := s.AddLocale("ja")
+	if !assert.Error(t, err, `AddLocale should fail`) {
+		return
+	}
+	assert.False(t, s.HasLocale("ja"), `locale should not be added`)
+
+	merr, ok := err.(MultiError)
+	if !assert.True(t, ok, `error should be a MultiError`) {
+		return
+	}
+	if assert.Len(t, merr.Errors(), 2, `every failure should be reported`) {
+		assert.Contains(t, merr.Errors()[0].Error(), "domain alpha")
+		assert.Contains(t, merr.Errors()[1].Error(), "domain zulu")
+	}
+	assert.Contains(t, err.Error(), "2 errors occurred")
+	assert.True(t, errors.Is(err, ErrDomainNotFound), `error should be ErrDomainNotFound`)
+}
+
+func TestLocaleSetMixedCharsets(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/utf8.po": []byte(`msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+
+msgid "Summer"
+msgstr "Été"
+`),
+		"fr/LC_MESSAGES/latin1.po": []byte("msgid \"\"\n" +
+			"msgstr \"\"\n" +
+			"\"Content-Type: text/plain; charset=ISO-8859-1\\n\"\n" +
+			"\n" +
+			"msgctxt \"season\"\n" +
+			"msgid \"Summer\"\n" +
+			"msgstr \"\xc9t\xe9\"\n"),
+		"fr/LC_MESSAGES/cp1252.mo": buildMO(binary.LittleEndian, map[string]string{
+			"":       "Content-Type: text/plain; charset=windows-1252\n",
+			"Quotes": "\x93\xc9t\xe9\x94 \x80",
+		}),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src), WithStrictParsing(true))
+	for _, domain := range []string{"utf8", "latin1", "cp1252"} {
+		s.AddDomain(domain)
+	}
+	if !assert.NoError(t, s.AddLocale("fr"), `AddLocale should succeed`) {
+		return
+	}
+
+	l, _ := s.GetLocale("fr")
+	assert.Equal(t, "Été", l.GetD("utf8", "Summer"))
+	assert.Equal(t, "Été", l.GetDC("latin1", "Summer", "season"))
+	assert.Equal(t, "“Été” €", l.GetD("cp1252", "Quotes"))
+
+	d, ok := l.(DomainLocale).Domain("latin1")
+	if assert.True(t, ok, `domain should exist`) {
+		assert.Equal(t, "text/plain; charset=UTF-8", d.po.Header("Content-Type"), `charset should be updated`)
+	}
+}
+
+func TestLocaleSetFallbackLocale(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "app.greeting"
+msgstr "Hello"
+`),
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "app.greeting"
+msgstr "こんにちは"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	s.AddDomain("default")
+	s.SetFallbackLocale("en")
+
+	// The fallback is resolved lazily, so it may be added afterwards
+	l, err := s.GetLocale("fr")
+	assert.True(t, errors.Is(err, ErrLocaleNotFound), `GetLocale should report the miss`)
+	assert.IsType(t, &NullLocale{}, l, `NullLocale should be returned until the fallback is added`)
+
+	for _, name := range []string{"en", "ja"} {
+		if !assert.NoError(t, s.AddLocale(name), `AddLocale should succeed`) {
+			return
+		}
+	}
+
+	l, err = s.GetLocale("fr")
+	assert.True(t, errors.Is(err, ErrLocaleNotFound), `GetLocale should report the miss`)
+	assert.Equal(t, "Hello", l.Get("app.greeting"), `fallback locale should be returned`)
+
+	l, err = s.GetLocale("ja")
+	if assert.NoError(t, err, `GetLocale should succeed`) {
+		assert.Equal(t, "こんにちは", l.Get("app.greeting"))
+	}
+
+	tr := s.Translator("fr", "de")
+	assert.Equal(t, "en", tr.Lang(), `Translator should use the fallback locale`)
+	assert.Equal(t, "Hello", tr.Get("app.greeting"))
+
+	_, name := s.Match("fr-FR, de;q=0.5")
+	assert.Equal(t, "en", name, `Match should use the fallback locale`)
+
+	s.SetFallbackLocale("")
+	l, _ = s.GetLocale("fr")
+	assert.Equal(t, "app.greeting", l.Get("app.greeting"), `removed fallback should not be used`)
+}
+
+func TestLocaleSetAddLocaleWithOptions(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hello"
+`),
+		"en/LC_MESSAGES/marketing.po": []byte(`
+msgid "Hello"
+msgstr "Hi there!"
+`),
+		"ja/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "こんにちは"
+`),
+	})
+	frSrc := NewMapSource(map[string][]byte{
+		"fr/default.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src))
+	s.AddDomain("default")
+	s.AddDomain("marketing")
+
+	if !assert.NoError(t, s.AddLocaleWithOptions("en", WithDefaultDomain("marketing")), `AddLocaleWithOptions should succeed`) {
+		return
+	}
+	if !assert.NoError(t, s.AddLocaleWithOptions("ja", WithIgnoreMissingDomains(true)), `AddLocaleWithOptions should succeed`) {
+		return
+	}
+	assert.Equal(t, []string{"marketing"}, s.MissingDomains("ja"), `per-locale WithIgnoreMissingDomains should be honored`)
+
+	l, _ := s.GetLocale("en")
+	assert.Equal(t, "Hi there!", l.Get("Hello"), `per-locale default domain should be used`)
+	l, _ = s.GetLocale("ja")
+	assert.Equal(t, "こんにちは", l.Get("Hello"), `shared options should be used`)
+
+	s.RemoveDomain("marketing")
+	if !assert.NoError(t, s.AddLocaleWithOptions("fr", WithSource(frSrc)), `AddLocaleWithOptions should succeed`) {
+		return
+	}
+	l, _ = s.GetLocale("fr")
+	assert.Equal(t, "Bonjour", l.Get("Hello"), `per-locale source should be used`)
+
+	// The shared options are left untouched
+	assert.Error(t, s.AddLocale("de"), `AddLocale should not use the per-locale options`)
+}
+
+func TestLocaleSetAddLocaleWithProgress(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour"
+`),
+		"fr/LC_MESSAGES/broken.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour
+`),
+		"fr/LC_MESSAGES/errors.po": []byte(`
+msgid "Not found"
+msgstr "Introuvable"
+`),
+	})
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src), WithStrictParsing(true))
+	for _, domain := range []string{"default", "broken", "errors", "missing"} {
+		s.AddDomain(domain)
+	}
+
+	var loaded, failed []string
+	err := s.AddLocaleWithProgress("fr", func(domain string, err error) {
+		if err != nil {
+			failed = append(failed, domain)
+			return
+		}
+		loaded = append(loaded, domain)
+	})
+	if assert.IsType(t, MultiError{}, err, `AddLocaleWithProgress should report the failures`) {
+		assert.Len(t, err.(MultiError).Errors(), 2, `both failures should be reported`)
+	}
+	assert.Equal(t, []string{"default", "errors"}, loaded, `loaded domains should be reported`)
+	assert.Equal(t, []string{"broken", "missing"}, failed, `failed domains should be reported`)
+
+	l, err := s.GetLocale("fr")
+	if assert.NoError(t, err, `locale should be added despite the failures`) {
+		assert.Equal(t, "Bonjour", l.Get("Hello"))
+		assert.Equal(t, "Introuvable", l.GetD("errors", "Not found"))
+		assert.False(t, l.(DomainLocale).HasDomain("broken"), `broken domain should not be loaded`)
+	}
+}
@@ -1,16 +1,94 @@
 package gettext
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
+
+	"github.com/pkg/errors"
 )
 
 func TestInterface(t *testing.T) {
 	var l Locale
 	l = NullLocale{}
+	l = StrictNullLocale{}
 	_ = l
+
+	for _, l := range []Locale{NewLocale("en"), NullLocale{}, StrictNullLocale{}} {
+		if _, ok := l.(LookupLocale); !ok {
+			t.Errorf("%T should implement LookupLocale", l)
+		}
+		if _, ok := l.(ContextLocale); !ok {
+			t.Errorf("%T should implement ContextLocale", l)
+		}
+		if _, ok := l.(PrinterLocale); !ok {
+			t.Errorf("%T should implement PrinterLocale", l)
+		}
+		if _, ok := l.(DomainLocale); !ok {
+			t.Errorf("%T should implement DomainLocale", l)
+		}
+		if _, ok := l.(CollateLocale); !ok {
+			t.Errorf("%T should implement CollateLocale", l)
+		}
+	}
+}
+
+// plainLocale only implements the methods of Locale
+type plainLocale struct {
+	Locale
+}
+
+func TestPlainLocale(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello, %s"
+msgstr "Bonjour, %s"
+`),
+	})
+
+	l := NewLocale("fr", WithSource(src))
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	tr := &Translator{lang: "fr", locale: plainLocale{l}}
+	if s := tr.GetRaw("Hello, %s"); s != "Bonjour, %s" {
+		t.Errorf("Expected 'Bonjour, %%s' but got '%s'", s)
+	}
+	if s, ok := tr.TryGetD("default", "Hello, %s", "John"); s != "Bonjour, John" || !ok {
+		t.Errorf("Expected 'Bonjour, John' to be found but got '%s' (%t)", s, ok)
+	}
+	if s, ok := tr.TryGetD("default", "Goodbye"); s != "Goodbye" || ok {
+		t.Errorf("Expected 'Goodbye' to be missing but got '%s' (%t)", s, ok)
+	}
+	if s, err := tr.GetDErr("default", "Hello, %s", "John"); s != "Bonjour, John" || err != nil {
+		t.Errorf("Expected 'Bonjour, John' but got '%s' (%v)", s, err)
+	}
+	if s := tr.GetContext(context.Background(), "Hello, %s", "John"); s != "Bonjour, John" {
+		t.Errorf("Expected 'Bonjour, John' but got '%s'", s)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tr.FprintfD(&buf, "default", "Hello, %s", "John"); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+	if buf.String() != "Bonjour, John" {
+		t.Errorf("Expected 'Bonjour, John' but got '%s'", buf.String())
+	}
+
+	// RemoveDomain is skipped for locales that do not support it
+	s := NewLocaleSet()
+	s.SetLocale("fr", plainLocale{l})
+	s.RemoveDomain("default")
+	if !l.(*locale).HasDomain("default") {
+		t.Errorf("Expected domain 'default' to be kept")
+	}
 }
 
 func TestLocale(t *testing.T) {
@@ -330,3 +408,1333 @@ msgstr[2] "And this is the second plural form: %s"
 	<-ac
 	<-rc
 }
+
+func TestLocaleReloadRace(t *testing.T) {
+	str := `
+msgid "My text"
+msgstr "Translated text"
+`
+
+	tmpdir, err := ioutil.TempDir("", "go-gettext-")
+	if err != nil {
+		t.Errorf("failed to create temporary directory: %s", err)
+		return
+	}
+	defer os.RemoveAll(tmpdir)
+
+	dirname := filepath.Join(tmpdir, "en")
+	if err := os.MkdirAll(dirname, os.ModePerm); err != nil {
+		t.Fatalf("Can't create test directory: %s", err.Error())
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dirname, "reload.po"), []byte(str), 0644); err != nil {
+		t.Fatalf("Can't write to test file: %s", err.Error())
+	}
+
+	l := NewLocale("en", WithSource(NewFileSystemSource(tmpdir)))
+	if err := l.AddDomain("reload"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	const iterations = 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				if err := l.AddDomain("reload"); err != nil {
+					t.Errorf("failed to reload domain: %s", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				// Readers must always see a fully loaded Po object
+				if tr := l.GetD("reload", "My text"); tr != "Translated text" {
+					t.Errorf("Expected 'Translated text' but got '%s'", tr)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestLocaleMapSource(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "My text"
+msgstr "Translated text"
+`),
+	})
+
+	l := NewLocale("en_US", WithSource(src))
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	if tr := l.Get("My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+
+	if err := l.AddDomain("missing"); err == nil {
+		t.Errorf("AddDomain should fail for missing domain")
+	}
+
+	if _, err := src.ReadFile("en/LC_MESSAGES/missing.po"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error, got %v", err)
+	}
+
+	if _, err := (NullSource{}).ReadFile("en/LC_MESSAGES/default.po"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error, got %v", err)
+	}
+}
+
+func TestDetectLocale(t *testing.T) {
+	names := []string{"LC_ALL", "LC_MESSAGES", "LANG"}
+	saved := make(map[string]string)
+	for _, name := range names {
+		saved[name] = os.Getenv(name)
+		os.Unsetenv(name)
+	}
+	defer func() {
+		for name, v := range saved {
+			os.Setenv(name, v)
+		}
+	}()
+
+	if l := DetectLocale(); l != "" {
+		t.Errorf("Expected '' but got '%s'", l)
+	}
+
+	os.Setenv("LANG", "ja_JP.UTF-8")
+	if l := DetectLocale(); l != "ja_JP" {
+		t.Errorf("Expected 'ja_JP' but got '%s'", l)
+	}
+
+	os.Setenv("LC_MESSAGES", "de_DE@euro")
+	if l := DetectLocale(); l != "de_DE" {
+		t.Errorf("Expected 'de_DE' but got '%s'", l)
+	}
+
+	os.Setenv("LC_ALL", "fr_FR.ISO-8859-1@euro")
+	if l := DetectLocale(); l != "fr_FR" {
+		t.Errorf("Expected 'fr_FR' but got '%s'", l)
+	}
+}
+
+func TestNormalizeLang(t *testing.T) {
+	cases := map[string]string{
+		"en":          "en",
+		"EN":          "en",
+		"en_US":       "en_US",
+		"en-US":       "en_US",
+		"EN_us":       "en_US",
+		"en-us":       "en_US",
+		"de_de.UTF-8": "de_DE.UTF-8",
+		"sr@latin":    "sr@latin",
+		"zh-Hant-TW":  "zh_Hant_TW",
+		"zh-hant-tw":  "zh_Hant_TW",
+		"sr-LATN":     "sr_Latn",
+		"sr_latn_rs":  "sr_Latn_RS",
+		"es-419":      "es_419",
+	}
+
+	for input, expected := range cases {
+		if v := NormalizeLang(input); v != expected {
+			t.Errorf("Expected '%s' for '%s' but got '%s'", expected, input, v)
+		}
+	}
+
+	src := NewMapSource(map[string][]byte{
+		"en_US/LC_MESSAGES/default.po": []byte(`
+msgid "My text"
+msgstr "Translated text"
+`),
+	})
+
+	l := NewLocale("EN-us", WithSource(src)).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	if tr := l.Get("My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+
+	if v := l.Lang(); v != "EN-us" {
+		t.Errorf("Expected 'EN-us' but got '%s'", v)
+	}
+
+	// Script codes
+	src = NewMapSource(map[string][]byte{
+		"zh_Hant_TW/LC_MESSAGES/default.po": []byte(`
+msgid "My text"
+msgstr "翻譯文字"
+`),
+	})
+	l = NewLocale("zh-hant-tw", WithSource(src)).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+	if tr := l.Get("My text"); tr != "翻譯文字" {
+		t.Errorf("Expected '翻譯文字' but got '%s'", tr)
+	}
+}
+
+func TestLocaleTryGetD(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello, %s"
+msgstr "Hi, %s"
+`),
+	})
+
+	l := NewLocale("en", WithSource(src)).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	tr, ok := l.TryGetD("default", "Hello, %s", "World")
+	if !ok || tr != "Hi, World" {
+		t.Errorf("Expected ('Hi, World', true) but got ('%s', %t)", tr, ok)
+	}
+
+	tr, ok = l.TryGetD("default", "Goodbye, %s", "World")
+	if ok || tr != "Goodbye, World" {
+		t.Errorf("Expected ('Goodbye, World', false) but got ('%s', %t)", tr, ok)
+	}
+
+	tr, ok = l.TryGetD("missing", "Hello, %s", "World")
+	if ok || tr != "Hello, World" {
+		t.Errorf("Expected ('Hello, World', false) but got ('%s', %t)", tr, ok)
+	}
+
+	tr, ok = NullLocale{}.TryGetD("default", "Hello, %s", "World")
+	if ok || tr != "Hello, World" {
+		t.Errorf("Expected ('Hello, World', false) but got ('%s', %t)", tr, ok)
+	}
+}
+
+func TestLocaleDomains(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`msgid "a"`),
+		"en/LC_MESSAGES/alpha.po":   []byte(`msgid "b"`),
+		"en/LC_MESSAGES/zulu.po":    []byte(`msgid "c"`),
+	})
+
+	l := NewLocale("en", WithSource(src)).(*locale)
+	if v := l.Domains(); len(v) != 0 {
+		t.Errorf("Expected no domains but got %v", v)
+	}
+
+	for _, dom := range []string{"zulu", "default", "alpha"} {
+		if err := l.AddDomain(dom); err != nil {
+			t.Fatalf("failed to add domain %s: %s", dom, err)
+		}
+	}
+
+	if v := l.Domains(); !reflect.DeepEqual(v, []string{"alpha", "default", "zulu"}) {
+		t.Errorf("Expected [alpha default zulu] but got %v", v)
+	}
+
+	if !l.HasDomain("alpha") {
+		t.Errorf("Expected HasDomain(alpha) to be true")
+	}
+
+	if l.HasDomain("missing") {
+		t.Errorf("Expected HasDomain(missing) to be false")
+	}
+}
+
+func TestLocaleNamedPlaceholders(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello, %{name}"
+msgstr "Hi, %{name}"
+`),
+	})
+
+	l := NewLocale("en", WithSource(src), WithNamedPlaceholders(true))
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	// go vet does not know about named placeholders
+	hello := "Hello, %{name}"
+
+	vars := map[string]interface{}{"name": "John"}
+	if tr := l.Get(hello, vars); tr != "Hi, John" {
+		t.Errorf("Expected 'Hi, John' but got '%s'", tr)
+	}
+
+	if tr := l.GetD("missing", hello, vars); tr != "Hello, John" {
+		t.Errorf("Expected 'Hello, John' but got '%s'", tr)
+	}
+}
+
+func TestLocaleDomain(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/errors.po": []byte(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Not found"
+msgstr "Could not find it"
+
+msgid "%d error"
+msgid_plural "%d errors"
+msgstr[0] "One error"
+msgstr[1] "%d errors occurred"
+
+msgctxt "http"
+msgid "Not found"
+msgstr "404 Not Found"
+`),
+	})
+
+	l := NewLocale("en", WithSource(src)).(*locale)
+	if _, ok := l.Domain("errors"); ok {
+		t.Errorf("Expected Domain(errors) to fail before AddDomain")
+	}
+
+	if err := l.AddDomain("errors"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	d, ok := l.Domain("errors")
+	if !ok {
+		t.Fatalf("Expected Domain(errors) to succeed")
+	}
+
+	if v := d.Name(); v != "errors" {
+		t.Errorf("Expected 'errors' but got '%s'", v)
+	}
+	if tr := d.Get("Not found"); tr != "Could not find it" {
+		t.Errorf("Expected 'Could not find it' but got '%s'", tr)
+	}
+	if tr := d.GetN("%d error", "%d errors", 3, 3); tr != "3 errors occurred" {
+		t.Errorf("Expected '3 errors occurred' but got '%s'", tr)
+	}
+	if tr := d.GetC("Not found", "http"); tr != "404 Not Found" {
+		t.Errorf("Expected '404 Not Found' but got '%s'", tr)
+	}
+	if tr := d.GetNC("%d error", "%d errors", 2, "http", 2); tr != "2 errors" {
+		t.Errorf("Expected '2 errors' but got '%s'", tr)
+	}
+
+	if _, ok := (NullLocale{}).Domain("errors"); ok {
+		t.Errorf("Expected NullLocale.Domain to fail")
+	}
+}
+
+func TestStrictNullLocale(t *testing.T) {
+	if err := (NullLocale{}).AddDomain("default"); err != nil {
+		t.Errorf("Expected NullLocale.AddDomain to succeed, got %s", err)
+	}
+	if err := (StrictNullLocale{}).AddDomain("default"); err == nil {
+		t.Errorf("Expected StrictNullLocale.AddDomain to fail")
+	}
+	if tr := (StrictNullLocale{}).Get("Hello, %s", "World"); tr != "Hello, World" {
+		t.Errorf("Expected 'Hello, World' but got '%s'", tr)
+	}
+}
+
+func TestLocaleMissingDomainVars(t *testing.T) {
+	l := NewLocale("en", WithSource(NullSource{}))
+
+	if tr := l.GetNDC("missing", "%d file", "%d files", 3, "ctx", 3); tr != "3 files" {
+		t.Errorf("Expected '3 files' but got '%s'", tr)
+	}
+
+	if tr := l.GetDC("missing", "Hello %s", "ctx", "John"); tr != "Hello John" {
+		t.Errorf("Expected 'Hello John' but got '%s'", tr)
+	}
+
+	if tr := l.GetND("missing", "%d file", "%d files", 3, 3); tr != "3 files" {
+		t.Errorf("Expected '3 files' but got '%s'", tr)
+	}
+}
+
+func TestLocaleStrictParsing(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hi"
+bogus
+`),
+	})
+
+	l := NewLocale("en", WithSource(src)).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Errorf("Expected AddDomain to succeed without strict parsing, but got %s", err)
+	}
+
+	l = NewLocale("en", WithSource(src), WithStrictParsing(true)).(*locale)
+	if err := l.AddDomain("default"); err == nil {
+		t.Errorf("Expected AddDomain to fail with strict parsing")
+	}
+	if l.HasDomain("default") {
+		t.Errorf("Expected the domain not to be loaded")
+	}
+
+	s := NewLocaleSet()
+	s.Options(WithSource(src), WithStrictParsing(true))
+	s.AddDomain("default")
+	if err := s.AddLocale("en"); err == nil {
+		t.Errorf("Expected AddLocale to fail with strict parsing")
+	}
+}
+
+func TestLocaleWithParser(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hi"
+bogus
+`),
+	})
+
+	// The options are not passed to the supplied parser
+	l := NewLocale("en", WithSource(src), WithStrictParsing(true), WithParser(NewParser()))
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("Expected AddDomain to succeed with a lenient parser, but got %s", err)
+	}
+	if tr := l.Get("Hello"); tr != "Hi" {
+		t.Errorf("Expected 'Hi' but got '%s'", tr)
+	}
+
+	l = NewLocale("en", WithSource(src), WithParser(NewParser(WithStrictParsing(true))))
+	if err := l.AddDomain("default"); err == nil {
+		t.Errorf("Expected AddDomain to fail with a strict parser")
+	}
+}
+
+func TestLocaleMOFiles(t *testing.T) {
+	mo := buildMO(binary.LittleEndian, map[string]string{"Hello": "Bonjour (mo)"})
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.mo": mo,
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour (po)"
+`),
+		"fr/LC_MESSAGES/other.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour (other po)"
+`),
+	})
+
+	l := NewLocale("fr_FR", WithSource(src))
+	for _, dom := range []string{"default", "other"} {
+		if err := l.AddDomain(dom); err != nil {
+			t.Fatalf("failed to add domain %s: %s", dom, err)
+		}
+	}
+
+	if tr := l.Get("Hello"); tr != "Bonjour (mo)" {
+		t.Errorf("Expected 'Bonjour (mo)' but got '%s'", tr)
+	}
+	if tr := l.GetD("other", "Hello"); tr != "Bonjour (other po)" {
+		t.Errorf("Expected 'Bonjour (other po)' but got '%s'", tr)
+	}
+}
+
+func TestLocaleDomainSource(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po":  []byte(`msgid "a"`),
+		"fr_CA/other.po":             []byte(`msgid "b"`),
+		"fr/LC_MESSAGES/compiled.mo": buildMO(binary.LittleEndian, nil),
+	})
+
+	l := NewLocale("fr_CA", WithSource(src)).(*locale)
+	if v := l.DomainSource("default"); v != "" {
+		t.Errorf("Expected an empty string before AddDomain but got '%s'", v)
+	}
+
+	expected := map[string]string{
+		"default":  filepath.Join("fr", "LC_MESSAGES", "default.po"),
+		"other":    filepath.Join("fr_CA", "other.po"),
+		"compiled": filepath.Join("fr", "LC_MESSAGES", "compiled.mo"),
+	}
+	for dom, filename := range expected {
+		if err := l.AddDomain(dom); err != nil {
+			t.Fatalf("failed to add domain %s: %s", dom, err)
+		}
+		if v := l.DomainSource(dom); v != filename {
+			t.Errorf("Expected '%s' for domain %s but got '%s'", filename, dom, v)
+		}
+	}
+}
+
+func TestLocaleSetDefaultDomain(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid "Title"
+msgstr "Default title"
+`),
+		"en/LC_MESSAGES/plugin.po": []byte(`
+msgid "Title"
+msgstr "Plugin title"
+`),
+	})
+
+	l := NewLocale("en", WithSource(src)).(*locale)
+	for _, dom := range []string{"default", "plugin"} {
+		if err := l.AddDomain(dom); err != nil {
+			t.Fatalf("failed to add domain %s: %s", dom, err)
+		}
+	}
+
+	if tr := l.Get("Title"); tr != "Default title" {
+		t.Errorf("Expected 'Default title' but got '%s'", tr)
+	}
+
+	if err := l.SetDefaultDomain("plugin"); err != nil {
+		t.Fatalf("failed to set default domain: %s", err)
+	}
+	if tr := l.Get("Title"); tr != "Plugin title" {
+		t.Errorf("Expected 'Plugin title' but got '%s'", tr)
+	}
+
+	if err := l.SetDefaultDomain("missing"); err == nil {
+		t.Errorf("Expected SetDefaultDomain to fail for a domain that is not loaded")
+	}
+	if tr := l.Get("Title"); tr != "Plugin title" {
+		t.Errorf("Expected the default domain to be unchanged, but got '%s'", tr)
+	}
+
+	// Switching while translating must be safe
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				l.SetDefaultDomain("default")
+			} else {
+				l.Get("Title")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestLocaleLayout(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"pt_BR/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Olá (LC_MESSAGES)"
+`),
+		"messages/default.pt_BR.po": []byte(`
+msgid "Hello"
+msgstr "Olá (messages)"
+`),
+		"messages/default.pt.po": []byte(`
+msgid "Bye"
+msgstr "Tchau (messages)"
+`),
+	})
+
+	l := NewLocale("pt-BR", WithSource(src)).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+	if tr := l.Get("Hello"); tr != "Olá (LC_MESSAGES)" {
+		t.Errorf("Expected 'Olá (LC_MESSAGES)' but got '%s'", tr)
+	}
+
+	l = NewLocale("pt-BR", WithSource(src), WithLayout("messages/{domain}.{lang}.po")).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+	if tr := l.Get("Hello"); tr != "Olá (messages)" {
+		t.Errorf("Expected 'Olá (messages)' but got '%s'", tr)
+	}
+	if v := l.DomainSource("default"); v != filepath.Join("messages", "default.pt_BR.po") {
+		t.Errorf("Expected 'messages/default.pt_BR.po' but got '%s'", v)
+	}
+
+	l = NewLocale("pt_PT", WithSource(src), WithLayout("messages/{domain}.{lang}.po", "messages/{domain}.{language}.po")).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+	if tr := l.Get("Bye"); tr != "Tchau (messages)" {
+		t.Errorf("Expected 'Tchau (messages)' but got '%s'", tr)
+	}
+
+	l = NewLocale("pt_BR", WithSource(src), WithLayout("{lang}/{domain}")).(*locale)
+	if err := l.AddDomain("default"); err == nil {
+		t.Errorf("Expected AddDomain to fail when the layout does not match")
+	} else if !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound but got '%s'", err)
+	}
+}
+
+func TestLocaleModifierAndCharset(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"sr@latin/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Zdravo"
+`),
+		"sr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Здраво"
+`),
+		"de_DE.UTF-8/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hallo (UTF-8)"
+`),
+		"de_DE/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Hallo"
+`),
+	})
+
+	testcases := []struct {
+		lang     string
+		expected string
+		filename string
+	}{
+		{"sr@latin", "Zdravo", "sr@latin/LC_MESSAGES/default.po"},
+		{"sr_RS@latin", "Zdravo", "sr@latin/LC_MESSAGES/default.po"},
+		{"sr_RS", "Здраво", "sr/LC_MESSAGES/default.po"},
+		{"sr@ijekavian", "Здраво", "sr/LC_MESSAGES/default.po"},
+		{"de_DE.UTF-8", "Hallo (UTF-8)", "de_DE.UTF-8/LC_MESSAGES/default.po"},
+		{"de_DE.ISO-8859-1", "Hallo", "de_DE/LC_MESSAGES/default.po"},
+		{"de_DE.ISO-8859-1@euro", "Hallo", "de_DE/LC_MESSAGES/default.po"},
+	}
+
+	for _, tc := range testcases {
+		l := NewLocale(tc.lang, WithSource(src)).(*locale)
+		if err := l.AddDomain("default"); err != nil {
+			t.Errorf("failed to add domain for %s: %s", tc.lang, err)
+			continue
+		}
+		if tr := l.Get("Hello"); tr != tc.expected {
+			t.Errorf("Expected '%s' for %s but got '%s'", tc.expected, tc.lang, tr)
+		}
+		if v := l.DomainSource("default"); v != filepath.FromSlash(tc.filename) {
+			t.Errorf("Expected '%s' for %s but got '%s'", tc.filename, tc.lang, v)
+		}
+	}
+}
+
+func TestLocaleFprintf(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello, %s"
+msgstr "Bonjour, %s"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+`),
+		"fr/LC_MESSAGES/errors.po": []byte(`
+msgid "Not found: %s"
+msgstr "Introuvable : %s"
+`),
+	})
+
+	l := NewLocale("fr", WithSource(src)).(*locale)
+	for _, dom := range []string{"default", "errors"} {
+		if err := l.AddDomain(dom); err != nil {
+			t.Fatalf("failed to add domain: %s", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	check := func(expected string, n int, err error) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("Expected no error but got %s", err)
+		}
+		if buf.String() != expected {
+			t.Errorf("Expected '%s' but got '%s'", expected, buf.String())
+		}
+		if n != buf.Len() {
+			t.Errorf("Expected %d bytes written but got %d", buf.Len(), n)
+		}
+		buf.Reset()
+	}
+
+	n, err := l.Fprintf(&buf, "Hello, %s", "John")
+	check("Bonjour, John", n, err)
+	n, err = l.FprintfD(&buf, "errors", "Not found: %s", "file")
+	check("Introuvable : file", n, err)
+	n, err = l.FprintfC(&buf, "Open", "menu")
+	check("Ouvrir", n, err)
+	n, err = l.FprintfDC(&buf, "errors", "Open", "menu")
+	check("Open", n, err)
+	n, err = l.FprintfD(&buf, "missing", "Hello, %s", "John")
+	check("Hello, John", n, err)
+	n, err = (NullLocale{}).Fprintf(&buf, "Hello, %s", "John")
+	check("Hello, John", n, err)
+}
+
+func TestLocaleGetRaw(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Progress: 100%"
+msgstr "Progression : 100 %"
+`),
+		"fr/LC_MESSAGES/errors.po": []byte(`
+msgid "Not found: %s"
+msgstr "Introuvable : %s"
+`),
+	})
+
+	l := NewLocale("fr", WithSource(src)).(*locale)
+	for _, dom := range []string{"default", "errors"} {
+		if err := l.AddDomain(dom); err != nil {
+			t.Fatalf("failed to add domain: %s", err)
+		}
+	}
+
+	if tr := l.GetRaw("Progress: 100%"); tr != "Progression : 100 %" {
+		t.Errorf("Expected 'Progression : 100 %%' but got '%s'", tr)
+	}
+	if tr := l.GetRawD("errors", "Not found: %s"); tr != "Introuvable : %s" {
+		t.Errorf("Expected 'Introuvable : %%s' but got '%s'", tr)
+	}
+	if tr := l.GetRawD("missing", "50%"); tr != "50%" {
+		t.Errorf("Expected '50%%' but got '%s'", tr)
+	}
+	if tr := (NullLocale{}).GetRaw("50%"); tr != "50%" {
+		t.Errorf("Expected '50%%' but got '%s'", tr)
+	}
+}
+
+func TestLocaleLazyDomains(t *testing.T) {
+	files := map[string][]byte{
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour"
+`),
+		"fr/LC_MESSAGES/errors.po": []byte(`
+msgid "Not found"
+msgstr "Introuvable"
+`),
+	}
+
+	var mu sync.Mutex
+	reads := make(map[string]int)
+	src := SourceFunc(func(name string) ([]byte, error) {
+		mu.Lock()
+		reads[name]++
+		data, ok := files[name]
+		mu.Unlock()
+
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return data, nil
+	})
+
+	l := NewLocale("fr", WithSource(src), WithLazyDomains(true)).(*locale)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tr := l.Get("Hello"); tr != "Bonjour" {
+				t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+			}
+			if tr := l.GetD("errors", "Not found"); tr != "Introuvable" {
+				t.Errorf("Expected 'Introuvable' but got '%s'", tr)
+			}
+			if tr := l.GetD("missing", "Not found"); tr != "Not found" {
+				t.Errorf("Expected 'Not found' but got '%s'", tr)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, name := range []string{"fr/LC_MESSAGES/default.po", "fr/LC_MESSAGES/errors.po"} {
+		if reads[name] != 1 {
+			t.Errorf("Expected %s to be read once but it was read %d times", name, reads[name])
+		}
+	}
+	// Failed loadings are attempted again
+	if reads["fr/LC_MESSAGES/missing.po"] < 1 {
+		t.Errorf("Expected missing.po to be read")
+	}
+	if !l.HasDomain("errors") {
+		t.Errorf("Expected domain 'errors' to be loaded")
+	}
+	if l.HasDomain("missing") {
+		t.Errorf("Expected domain 'missing' not to be loaded")
+	}
+
+	// A removed domain is loaded again by the next lookup
+	l.RemoveDomain("errors")
+	if l.HasDomain("errors") {
+		t.Errorf("Expected domain 'errors' to be removed")
+	}
+	if tr := l.GetD("errors", "Not found"); tr != "Introuvable" {
+		t.Errorf("Expected 'Introuvable' after RemoveDomain but got '%s'", tr)
+	}
+	if reads["fr/LC_MESSAGES/errors.po"] != 2 {
+		t.Errorf("Expected errors.po to be read again but it was read %d times", reads["fr/LC_MESSAGES/errors.po"])
+	}
+
+	// A loading that failed is attempted again by the next lookup
+	if _, err := l.GetDErr("later", "Later"); err == nil {
+		t.Errorf("Expected an error for a domain without catalog")
+	}
+	mu.Lock()
+	files["fr/LC_MESSAGES/later.po"] = []byte(`
+msgid "Later"
+msgstr "Plus tard"
+`)
+	mu.Unlock()
+	if tr, err := l.GetDErr("later", "Later"); err != nil || tr != "Plus tard" {
+		t.Errorf("Expected 'Plus tard' after the catalog was added but got '%s' (%v)", tr, err)
+	}
+
+	// Without the option, domains must be added explicitly
+	l = NewLocale("fr", WithSource(src)).(*locale)
+	if tr := l.Get("Hello"); tr != "Hello" {
+		t.Errorf("Expected 'Hello' but got '%s'", tr)
+	}
+}
+
+// dirOnlySource is a DirSource that is not a GlobSource
+type dirOnlySource struct {
+	src *MapSource
+}
+
+func (s dirOnlySource) ReadFile(name string) ([]byte, error) {
+	return s.src.ReadFile(name)
+}
+
+func (s dirOnlySource) ReadDir(dir string) ([]string, error) {
+	return s.src.ReadDir(dir)
+}
+
+func TestLocaleAddDomainGlob(t *testing.T) {
+	files := map[string][]byte{
+		"fr/LC_MESSAGES/svc-billing.po": []byte(`
+msgid "Invoice"
+msgstr "Facture"
+`),
+		"fr/LC_MESSAGES/svc-users.mo": buildMO(binary.LittleEndian, map[string]string{"User": "Utilisateur"}),
+		"fr_CA/LC_MESSAGES/svc-orders.po": []byte(`
+msgid "Order"
+msgstr "Commande"
+`),
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour"
+`),
+		"de/LC_MESSAGES/svc-stock.po": []byte(`
+msgid "Stock"
+msgstr "Lager"
+`),
+	}
+
+	l := NewLocale("fr_CA", WithSource(NewMapSource(files))).(*locale)
+	if err := l.AddDomainGlob("svc-*"); err != nil {
+		t.Fatalf("failed to add domains: %s", err)
+	}
+
+	if domains := l.Domains(); !reflect.DeepEqual(domains, []string{"svc-billing", "svc-orders", "svc-users"}) {
+		t.Errorf("Expected domains svc-billing, svc-orders and svc-users but got %v", domains)
+	}
+	if tr := l.GetD("svc-billing", "Invoice"); tr != "Facture" {
+		t.Errorf("Expected 'Facture' but got '%s'", tr)
+	}
+	if tr := l.GetD("svc-users", "User"); tr != "Utilisateur" {
+		t.Errorf("Expected 'Utilisateur' but got '%s'", tr)
+	}
+	if tr := l.GetD("svc-orders", "Order"); tr != "Commande" {
+		t.Errorf("Expected 'Commande' but got '%s'", tr)
+	}
+
+	if err := l.AddDomainGlob("missing-*"); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound but got %v", err)
+	}
+
+	// Sources that cannot list files are reported
+	src := SourceFunc(func(name string) ([]byte, error) {
+		return NewMapSource(files).ReadFile(name)
+	})
+	l = NewLocale("fr", WithSource(src)).(*locale)
+	if err := l.AddDomainGlob("svc-*"); !errors.Is(err, ErrGlobNotSupported) {
+		t.Errorf("Expected ErrGlobNotSupported but got %v", err)
+	}
+
+	// Sources that can only list directories are read
+	l = NewLocale("fr_CA", WithSource(dirOnlySource{NewMapSource(files)})).(*locale)
+	if err := l.AddDomainGlob("svc-*"); err != nil {
+		t.Fatalf("failed to add domains: %s", err)
+	}
+	if domains := l.Domains(); !reflect.DeepEqual(domains, []string{"svc-billing", "svc-orders", "svc-users"}) {
+		t.Errorf("Expected domains svc-billing, svc-orders and svc-users but got %v", domains)
+	}
+
+	// Custom layouts
+	l = NewLocale("fr", WithSource(NewMapSource(map[string][]byte{
+		"svc-billing/fr.po": files["fr/LC_MESSAGES/svc-billing.po"],
+		"svc-billing/de.po": files["de/LC_MESSAGES/svc-stock.po"],
+	})), WithLayout("{domain}/{lang}.po")).(*locale)
+	if err := l.AddDomainGlob("svc-*"); err != nil {
+		t.Fatalf("failed to add domains: %s", err)
+	}
+	if tr := l.GetD("svc-billing", "Invoice"); tr != "Facture" {
+		t.Errorf("Expected 'Facture' but got '%s'", tr)
+	}
+}
+
+func TestLocaleEmptyDomain(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/messages.po": []byte(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+`),
+	})
+
+	l := NewLocale("fr", WithSource(src), WithDefaultDomain("messages")).(*locale)
+	if err := l.AddDomain("messages"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	if tr := l.GetD("", "Hello"); tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+	if tr := l.GetND("", "%d file", "%d files", 2, 2); tr != "2 fichiers" {
+		t.Errorf("Expected '2 fichiers' but got '%s'", tr)
+	}
+	if tr := l.GetDC("", "Open", "menu"); tr != "Ouvrir" {
+		t.Errorf("Expected 'Ouvrir' but got '%s'", tr)
+	}
+	if tr := l.GetNDC("", "Open", "Open", 1, "menu"); tr != "Ouvrir" {
+		t.Errorf("Expected 'Ouvrir' but got '%s'", tr)
+	}
+	if tr, ok := l.TryGetD("", "Hello"); tr != "Bonjour" || !ok {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+	if _, err := l.GetDErr("", "Hello"); err != nil {
+		t.Errorf("Expected no error but got %s", err)
+	}
+	if d, ok := l.Domain(""); !ok || d.Name() != "messages" {
+		t.Errorf("Expected the default domain to be returned")
+	}
+	if f := l.DomainSource(""); f != "fr/LC_MESSAGES/messages.po" {
+		t.Errorf("Expected 'fr/LC_MESSAGES/messages.po' but got '%s'", f)
+	}
+}
+
+func TestGlobalLocale(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello, %s"
+msgstr "Bonjour, %s"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgctxt "menu"
+msgid "%d item"
+msgid_plural "%d items"
+msgstr[0] "%d élément"
+msgstr[1] "%d éléments"
+`),
+		"fr/LC_MESSAGES/errors.po": []byte(`
+msgid "Not found"
+msgstr "Introuvable"
+`),
+	})
+
+	l := NewLocale("fr", WithSource(src))
+	for _, dom := range []string{"default", "errors"} {
+		if err := l.AddDomain(dom); err != nil {
+			t.Fatalf("failed to add domain: %s", err)
+		}
+	}
+
+	if tr := Gettext("Hello, %s", "John"); tr != "Hello, John" {
+		t.Errorf("Expected 'Hello, John' before SetLocale but got '%s'", tr)
+	}
+
+	SetLocale(l)
+	defer SetLocale(nil)
+
+	if CurrentLocale() != l {
+		t.Errorf("Expected CurrentLocale to return the locale set by SetLocale")
+	}
+
+	tests := []struct {
+		got      string
+		expected string
+	}{
+		{Gettext("Hello, %s", "John"), "Bonjour, John"},
+		{NGettext("%d file", "%d files", 2, 2), "2 fichiers"},
+		{DGettext("errors", "Not found"), "Introuvable"},
+		{DNGettext("errors", "%d file", "%d files", 2, 2), "2 files"},
+		{PGettext("menu", "Open"), "Ouvrir"},
+		{NPGettext("menu", "%d item", "%d items", 1, 1), "1 élément"},
+		{DPGettext("default", "menu", "Open"), "Ouvrir"},
+		{DNPGettext("default", "menu", "%d item", "%d items", 3, 3), "3 éléments"},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("Expected '%s' but got '%s'", test.expected, test.got)
+		}
+	}
+
+	SetLocale(nil)
+	if tr := Gettext("Hello, %s", "John"); tr != "Hello, John" {
+		t.Errorf("Expected 'Hello, John' after resetting the locale but got '%s'", tr)
+	}
+}
+
+func TestBindTextdomain(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "go-gettext-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	files := map[string]string{
+		"app/fr/LC_MESSAGES/app.po": `
+msgid "Hello"
+msgstr "Bonjour"
+`,
+		"app/de/LC_MESSAGES/app.po": `
+msgid "Hello"
+msgstr "Hallo"
+`,
+		"lib/fr/LC_MESSAGES/lib.po": `
+msgid "Not found"
+msgstr "Introuvable"
+`,
+	}
+	for name, content := range files {
+		fn := filepath.Join(tmpdir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(fn, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+	}
+
+	defer func() {
+		globalMu.Lock()
+		globalDirs = make(map[string]string)
+		globalTextdomain = "default"
+		globalMu.Unlock()
+		SetLocale(nil)
+	}()
+
+	BindTextdomain("app", filepath.Join(tmpdir, "app"))
+	Textdomain("app")
+	SetLanguage("fr_FR")
+
+	if tr := Gettext("Hello"); tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+	if tr := DGettext("lib", "Not found"); tr != "Not found" {
+		t.Errorf("Expected 'Not found' for an unbound domain but got '%s'", tr)
+	}
+
+	// Bindings take effect immediately
+	BindTextdomain("lib", filepath.Join(tmpdir, "lib"))
+	if tr := DGettext("lib", "Not found"); tr != "Introuvable" {
+		t.Errorf("Expected 'Introuvable' but got '%s'", tr)
+	}
+
+	SetLanguage("de")
+	if tr := Gettext("Hello"); tr != "Hallo" {
+		t.Errorf("Expected 'Hallo' but got '%s'", tr)
+	}
+	if lang := CurrentLocale().(DomainLocale).Lang(); lang != "de" {
+		t.Errorf("Expected language 'de' but got '%s'", lang)
+	}
+
+	// SetLocale takes precedence over the bindings
+	SetLocale(nil)
+	Textdomain("lib")
+	if tr := Gettext("Hello"); tr != "Hello" {
+		t.Errorf("Expected 'Hello' but got '%s'", tr)
+	}
+}
+
+func TestLocaleGetContext(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour"
+`),
+		"fr/LC_MESSAGES/errors.po": []byte(`
+msgid "Not found: %s"
+msgstr "Introuvable : %s"
+`),
+	})
+
+	l := NewLocale("fr", WithSource(src), WithLazyDomains(true)).(*locale)
+
+	// A canceled context prevents the loading, which is retried later
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if tr := l.GetContext(ctx, "Hello"); tr != "Hello" {
+		t.Errorf("Expected 'Hello' but got '%s'", tr)
+	}
+	if l.HasDomain("default") {
+		t.Errorf("Expected domain 'default' not to be loaded")
+	}
+
+	if tr := l.GetContext(context.Background(), "Hello"); tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+	if tr := l.GetDContext(context.Background(), "errors", "Not found: %s", "file"); tr != "Introuvable : file" {
+		t.Errorf("Expected 'Introuvable : file' but got '%s'", tr)
+	}
+
+	// Domains that are already loaded are used even if ctx is canceled
+	if tr := l.GetContext(ctx, "Hello"); tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+
+	// Canceling the lookup that started a loading does not affect the
+	// other lookups that wait for it
+	var startOnce sync.Once
+	started, release := make(chan struct{}), make(chan struct{})
+	slow := SourceFunc(func(name string) ([]byte, error) {
+		startOnce.Do(func() { close(started) })
+		<-release
+		return src.ReadFile(name)
+	})
+	l = NewLocale("fr", WithSource(slow), WithLazyDomains(true)).(*locale)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	first := make(chan string)
+	go func() {
+		first <- l.GetContext(ctx, "Hello")
+	}()
+	<-started
+	second := make(chan string)
+	go func() {
+		second <- l.GetContext(context.Background(), "Hello")
+	}()
+
+	cancel()
+	if tr := <-first; tr != "Hello" {
+		t.Errorf("Expected 'Hello' for the canceled lookup but got '%s'", tr)
+	}
+	close(release)
+	if tr := <-second; tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' for the other lookup but got '%s'", tr)
+	}
+	if tr := (NullLocale{}).GetContext(ctx, "Hello, %s", "John"); tr != "Hello, John" {
+		t.Errorf("Expected 'Hello, John' but got '%s'", tr)
+	}
+}
+
+func TestLocaleGetDErr(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour"
+`),
+		"fr/LC_MESSAGES/broken.po": []byte(`
+msgid "Hello"
+msgstr "Bonjour
+`),
+	})
+
+	l := NewLocale("fr", WithSource(src)).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	// Domain loaded, with a translation
+	if tr, err := l.GetDErr("default", "Hello"); tr != "Bonjour" || err != nil {
+		t.Errorf("Expected 'Bonjour' and no error but got '%s' and %v", tr, err)
+	}
+	// Domain loaded, without the key
+	if tr, err := l.GetDErr("default", "Goodbye"); tr != "Goodbye" || err != nil {
+		t.Errorf("Expected 'Goodbye' and no error but got '%s' and %v", tr, err)
+	}
+	// Domain not loaded
+	tr, err := l.GetDErr("errors", "Not found: %s", "file")
+	if tr != "Not found: file" {
+		t.Errorf("Expected 'Not found: file' but got '%s'", tr)
+	}
+	if !errors.Is(err, ErrDomainNotLoaded) {
+		t.Errorf("Expected ErrDomainNotLoaded but got %v", err)
+	}
+
+	// With lazy loading, the error tells why the domain could not be loaded
+	l = NewLocale("fr", WithSource(src), WithLazyDomains(true), WithStrictParsing(true)).(*locale)
+	if tr, err := l.GetDErr("default", "Hello"); tr != "Bonjour" || err != nil {
+		t.Errorf("Expected 'Bonjour' and no error but got '%s' and %v", tr, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := l.GetDErr("errors", "Not found"); !errors.Is(err, ErrDomainNotFound) {
+			t.Errorf("Expected ErrDomainNotFound but got %v", err)
+		}
+	}
+	if _, err := l.GetDErr("broken", "Hello"); err == nil || errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected a parse error but got %v", err)
+	}
+
+	if tr, err := (NullLocale{}).GetDErr("default", "Hello, %s", "John"); tr != "Hello, John" || !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected 'Hello, John' and ErrDomainNotFound from NullLocale but got '%s' and %v", tr, err)
+	}
+	if _, err := (StrictNullLocale{}).GetDErr("default", "Hello"); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound from StrictNullLocale but got %v", err)
+	}
+}
+
+func TestLocaleBaseLocale(t *testing.T) {
+	src := NewMapSource(map[string][]byte{
+		"en/LC_MESSAGES/default.po": []byte(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "app.title"
+msgstr "My Application"
+
+msgid "app.greeting"
+msgstr "Hello, %s"
+
+msgid "file.count"
+msgid_plural "file.count.plural"
+msgstr[0] "%d file"
+msgstr[1] "%d files"
+
+msgctxt "menu"
+msgid "menu.open"
+msgstr "Open"
+
+msgctxt "menu"
+msgid "menu.items"
+msgid_plural "menu.items.plural"
+msgstr[0] "%d item"
+msgstr[1] "%d items"
+`),
+		"fr/LC_MESSAGES/default.po": []byte(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "app.title"
+msgstr "Mon application"
+
+msgid "app.greeting"
+msgstr ""
+
+msgid "file.count"
+msgid_plural "file.count.plural"
+msgstr[0] "%d fichier"
+
+msgctxt "menu"
+msgid "menu.open"
+msgstr ""
+
+msgctxt "menu"
+msgid "menu.items"
+msgid_plural "menu.items.plural"
+msgstr[0] "%d élément"
+`),
+	})
+
+	base := NewLocale("en", WithSource(src)).(*locale)
+	if err := base.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	l := NewLocale("fr", WithSource(src), WithBaseLocale(base)).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+
+	if tr := l.Get("app.title"); tr != "Mon application" {
+		t.Errorf("Expected 'Mon application' but got '%s'", tr)
+	}
+	if tr := l.Get("app.greeting", "John"); tr != "Hello, John" {
+		t.Errorf("Expected 'Hello, John' but got '%s'", tr)
+	}
+	if tr := l.GetN("file.count", "file.count.plural", 1, 1); tr != "1 fichier" {
+		t.Errorf("Expected '1 fichier' but got '%s'", tr)
+	}
+	if tr := l.GetN("file.count", "file.count.plural", 3, 3); tr != "3 files" {
+		t.Errorf("Expected '3 files' but got '%s'", tr)
+	}
+	if tr := l.GetD("other", "app.title"); tr != "app.title" {
+		t.Errorf("Expected 'app.title' for a domain that is not loaded but got '%s'", tr)
+	}
+	if tr := l.Get("app.unknown"); tr != "app.unknown" {
+		t.Errorf("Expected 'app.unknown' but got '%s'", tr)
+	}
+
+	// The msgid is not a format string, which vet would report on *locale
+	if tr, ok := LookupLocale(l).TryGetD("default", "app.greeting", "John"); !ok || tr != "Hello, John" {
+		t.Errorf("Expected 'Hello, John' and true but got '%s' and %t", tr, ok)
+	}
+	if tr, ok := l.TryGetD("default", "app.unknown"); ok || tr != "app.unknown" {
+		t.Errorf("Expected 'app.unknown' and false but got '%s' and %t", tr, ok)
+	}
+
+	// The lookups in a context use the base locale as well
+	if tr := l.GetC("menu.open", "menu"); tr != "Open" {
+		t.Errorf("Expected 'Open' but got '%s'", tr)
+	}
+	if tr := l.GetNC("menu.items", "menu.items.plural", 1, "menu", 1); tr != "1 élément" {
+		t.Errorf("Expected '1 élément' but got '%s'", tr)
+	}
+	if tr := l.GetNDC("default", "menu.items", "menu.items.plural", 3, "menu", 3); tr != "3 items" {
+		t.Errorf("Expected '3 items' but got '%s'", tr)
+	}
+	if tr := l.GetDC("default", "menu.unknown", "menu"); tr != "menu.unknown" {
+		t.Errorf("Expected 'menu.unknown' but got '%s'", tr)
+	}
+	var buf bytes.Buffer
+	if _, err := l.FprintfC(&buf, "menu.open", "menu"); err != nil || buf.String() != "Open" {
+		t.Errorf("Expected 'Open' but got '%s' (%v)", buf.String(), err)
+	}
+
+	l = NewLocale("fr", WithSource(src)).(*locale)
+	if err := l.AddDomain("default"); err != nil {
+		t.Fatalf("failed to add domain: %s", err)
+	}
+	if tr := l.GetN("file.count", "file.count.plural", 3, 3); tr != "file.count.plural%!(EXTRA int=3)" {
+		t.Errorf("Expected the key without a base locale but got '%s'", tr)
+	}
+}
@@ -0,0 +1,260 @@
+package gettext
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	moMagicLittleEndian = 0x950412de
+	moMagicBigEndian    = 0xde120495
+
+	// moHeaderSize is the size of the fixed part of the MO header that
+	// we need: magic, revision, number of strings, and the offsets of
+	// the tables of original and translated strings
+	moHeaderSize = 20
+)
+
+// contextKey returns the key under which an entry is stored in a .mo
+// file: the msgid, prefixed by the msgctxt and an EOT byte if there is
+// a context
+func contextKey(ctxt, id string) string {
+	if ctxt == "" {
+		return id
+	}
+	return ctxt + "\x04" + id
+}
+
+// splitContextKey splits a key created by contextKey into the msgctxt
+// and the msgid
+func splitContextKey(key string) (string, string) {
+	if sep := strings.IndexByte(key, '\x04'); sep > -1 {
+		return key[:sep], key[sep+1:]
+	}
+	return "", key
+}
+
+// ParseMOFile parses the compiled (.mo) catalog in the file f
+func (p *Parser) ParseMOFile(f string) (*Po, error) {
+	data, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, `mo: failed to read file %s`, f)
+	}
+	return p.ParseMO(data)
+}
+
+// ParseMO parses a compiled (.mo) catalog, as generated by msgfmt.
+// The resulting Po object behaves the same as one that was parsed from
+// the corresponding .po file, except that it does not hold comments or
+// obsolete entries, as they are not stored in .mo files.
+//
+// Errors in the binary structure of the data are always reported. Errors
+// in the headers, duplicate entries and invalid plural entries are only
+// reported if strict parsing is enabled, as for .po files.
+func (p *Parser) ParseMO(data []byte) (*Po, error) {
+	if len(data) < moHeaderSize {
+		return nil, errors.New(`mo: file is too short`)
+	}
+
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(data) {
+	case moMagicLittleEndian:
+		order = binary.LittleEndian
+	case moMagicBigEndian:
+		order = binary.BigEndian
+	default:
+		return nil, errors.New(`mo: invalid magic number`)
+	}
+
+	// Only the major revision matters: minor revisions are compatible
+	if major := order.Uint32(data[4:]) >> 16; major > 1 {
+		return nil, errors.Errorf(`mo: unsupported revision %d`, major)
+	}
+
+	count := order.Uint32(data[8:])
+	origTable := order.Uint32(data[12:])
+	transTable := order.Uint32(data[16:])
+
+	// The number of strings comes from the file, so it must be checked
+	// against the size of the data before anything is allocated for it
+	for _, table := range []uint32{origTable, transTable} {
+		if uint64(table)+uint64(count)*8 > uint64(len(data)) {
+			return nil, errors.New(`mo: string table out of range`)
+		}
+	}
+	n := int(count)
+
+	// readString reads the i-th string of the table at the given offset
+	readString := func(table uint32, i int) (string, error) {
+		pos := uint64(table) + uint64(i)*8
+		if pos+8 > uint64(len(data)) {
+			return "", errors.New(`mo: string table out of range`)
+		}
+		length := uint64(order.Uint32(data[pos:]))
+		offset := uint64(order.Uint32(data[pos+4:]))
+		if offset+length > uint64(len(data)) {
+			return "", errors.New(`mo: string out of range`)
+		}
+		return string(data[offset : offset+length]), nil
+	}
+
+	ctx := p.newParseCtx(context.Background(), n)
+
+	for i := 0; i < n; i++ {
+		key, err := readString(origTable, i)
+		if err != nil {
+			return nil, errors.Wrapf(err, `mo: failed to read original string %d`, i)
+		}
+		value, err := readString(transTable, i)
+		if err != nil {
+			return nil, errors.Wrapf(err, `mo: failed to read translated string %d`, i)
+		}
+
+		if key == "" {
+			ctx.rawHeaders = value
+			continue
+		}
+
+		t := newTranslation()
+		t.ctx, key = splitContextKey(key)
+		if sep := strings.IndexByte(key, '\x00'); sep > -1 {
+			key, t.PluralID = key[:sep], key[sep+1:]
+		}
+		t.id = key
+
+		if t.PluralID == "" {
+			t.Trs.Set(0, value)
+		} else {
+			for idx, form := range strings.Split(value, "\x00") {
+				t.Trs.Set(idx, form)
+			}
+		}
+
+		ctx.curTranslation = t
+		ctx.curContext = t.ctx
+		ctx.pop()
+		if ctx.dupErr != nil {
+			return nil, errors.Errorf(`mo: string %d: duplicate msgid %s`, i, strconv.Quote(t.id))
+		}
+	}
+
+	if err := ctx.parseHeaders(); err != nil {
+		if p.strict {
+			return nil, errors.Wrap(err, `mo: failed to parse header`)
+		}
+	}
+	if err := ctx.convertCharset(); err != nil {
+		if p.strict {
+			return nil, errors.Wrap(err, `mo: failed to convert charset`)
+		}
+	}
+
+	if p.strict {
+		if errs := ctx.po.Validate(); len(errs) > 0 {
+			return nil, MultiError(errs)
+		}
+	}
+
+	return ctx.po, nil
+}
+
+// WriteMO writes the catalog to w in the compiled (.mo) format, as
+// msgfmt does. Like msgfmt, entries that have not been translated,
+// entries that are marked as fuzzy, and obsolete entries are left out.
+// The strings are sorted, but no hash table is written.
+func (po *Po) WriteMO(w io.Writer) error {
+	defer po.runlock(po.rlock())
+
+	entries := make(map[string]string)
+	if len(po.headers) > 0 {
+		var v string
+		for _, h := range po.headers {
+			v += h.name + ": " + h.value + "\n"
+		}
+		entries[""] = v
+	}
+
+	add := func(t *translation) {
+		if !t.translated(0) || t.fuzzy() {
+			return
+		}
+
+		key := contextKey(t.ctx, t.id)
+		if t.PluralID == "" {
+			v, _ := t.Trs.Get(0)
+			entries[key] = v
+			return
+		}
+
+		forms := make([]string, t.Trs.Len())
+		for i := range forms {
+			forms[i], _ = t.Trs.Get(i)
+		}
+		entries[key+"\x00"+t.PluralID] = strings.Join(forms, "\x00")
+	}
+	for _, t := range po.translations {
+		add(t)
+	}
+	for _, m := range po.contexts {
+		for _, t := range m {
+			add(t)
+		}
+	}
+
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	n := uint32(len(keys))
+	origTable := uint32(28)
+	transTable := origTable + n*8
+	offset := transTable + n*8
+
+	header := make([]byte, offset)
+	order := binary.LittleEndian
+	order.PutUint32(header[0:], moMagicLittleEndian)
+	order.PutUint32(header[8:], n)
+	order.PutUint32(header[12:], origTable)
+	order.PutUint32(header[16:], transTable)
+	order.PutUint32(header[24:], offset) // empty hash table
+
+	// Each string is followed by a NUL byte, which is not included in
+	// its length
+	for i, k := range keys {
+		order.PutUint32(header[origTable+uint32(i)*8:], uint32(len(k)))
+		order.PutUint32(header[origTable+uint32(i)*8+4:], offset)
+		offset += uint32(len(k)) + 1
+	}
+	for i, k := range keys {
+		v := entries[k]
+		order.PutUint32(header[transTable+uint32(i)*8:], uint32(len(v)))
+		order.PutUint32(header[transTable+uint32(i)*8+4:], offset)
+		offset += uint32(len(v)) + 1
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+	for _, k := range keys {
+		bw.WriteString(k)
+		bw.WriteByte(0)
+	}
+	for _, k := range keys {
+		bw.WriteString(entries[k])
+		bw.WriteByte(0)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, `mo: failed to write`)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package gettext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildMO creates a .mo file containing the given original/translated
+// string pairs, like msgfmt does
+func buildMO(order binary.ByteOrder, entries map[string]string) []byte {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	n := uint32(len(keys))
+	origTable := uint32(28)
+	transTable := origTable + n*8
+	offset := transTable + n*8
+
+	buf := make([]byte, offset)
+	order.PutUint32(buf[0:], moMagicLittleEndian)
+	order.PutUint32(buf[4:], 0)
+	order.PutUint32(buf[8:], n)
+	order.PutUint32(buf[12:], origTable)
+	order.PutUint32(buf[16:], transTable)
+
+	for i, k := range keys {
+		for j, s := range []string{k, entries[k]} {
+			pos := origTable + uint32(i)*8
+			if j == 1 {
+				pos = transTable + uint32(i)*8
+			}
+			order.PutUint32(buf[pos:], uint32(len(s)))
+			order.PutUint32(buf[pos+4:], uint32(len(buf)))
+			buf = append(buf, s...)
+			buf = append(buf, 0)
+		}
+	}
+	return buf
+}
+
+func TestParseMO(t *testing.T) {
+	entries := map[string]string{
+		"":                             "Language: fr\nPlural-Forms: nplurals=2; plural=(n > 1);\n",
+		"Hello":                        "Bonjour",
+		"One file\x00%d files":         "Un fichier\x00%d fichiers",
+		"menu\x04Open":                 "Ouvrir",
+		"menu\x04One item\x00%d items": "Un élément\x00%d éléments",
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		po, err := NewParser().ParseMO(buildMO(order, entries))
+		if !assert.NoError(t, err, `ParseMO should succeed for `+order.String()) {
+			return
+		}
+
+		assert.Equal(t, "fr", po.language)
+		assert.Equal(t, "Bonjour", po.Get("Hello"))
+		assert.Equal(t, "Un fichier", po.GetN("One file", "%d files", 1))
+		assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2))
+		assert.Equal(t, "Ouvrir", po.GetC("Open", "menu"))
+		assert.Equal(t, "Open", po.Get("Open"), `contextual entries should not be registered without context`)
+		assert.Equal(t, "3 éléments", po.GetNC("One item", "%d items", 3, "menu", 3))
+
+		v, ok := po.PluralID("One file")
+		assert.True(t, ok, `PluralID should succeed`)
+		assert.Equal(t, "%d files", v)
+	}
+}
+
+func TestParseMOErrors(t *testing.T) {
+	data := buildMO(binary.LittleEndian, map[string]string{"Hello": "Bonjour"})
+
+	_, err := NewParser().ParseMO(data[:10])
+	assert.Error(t, err, `short data should be rejected`)
+
+	bad := append([]byte(nil), data...)
+	bad[0] = 0
+	_, err = NewParser().ParseMO(bad)
+	assert.Error(t, err, `bad magic number should be rejected`)
+
+	bad = append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(bad[4:], 2<<16)
+	_, err = NewParser().ParseMO(bad)
+	assert.Error(t, err, `unknown major revision should be rejected`)
+
+	_, err = NewParser().ParseMO(data[:len(data)-4])
+	assert.Error(t, err, `truncated strings should be rejected`)
+
+	bad = append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(bad[8:], 1000)
+	_, err = NewParser().ParseMO(bad)
+	assert.Error(t, err, `truncated tables should be rejected`)
+
+	// A huge number of strings must be rejected before anything is
+	// allocated for them
+	bad = make([]byte, 28)
+	binary.LittleEndian.PutUint32(bad[0:], moMagicLittleEndian)
+	binary.LittleEndian.PutUint32(bad[8:], 0xffffffff)
+	binary.LittleEndian.PutUint32(bad[12:], 28)
+	binary.LittleEndian.PutUint32(bad[16:], 28)
+	_, err = NewParser().ParseMO(bad)
+	if assert.Error(t, err, `huge string count should be rejected`) {
+		assert.Equal(t, `mo: string table out of range`, err.Error())
+	}
+
+	// Strict parsing applies the same checks as for .po files
+	dup := bytes.Replace(buildMO(binary.LittleEndian, map[string]string{"Hello": "Bonjour", "Hellp": "Salut"}), []byte("Hellp"), []byte("Hello"), 1)
+	_, err = NewParser().ParseMO(dup)
+	assert.NoError(t, err, `duplicates should be accepted by default`)
+	_, err = NewParser(WithStrictParsing(true)).ParseMO(dup)
+	if assert.Error(t, err, `duplicates should be rejected in strict mode`) {
+		assert.Equal(t, `mo: string 1: duplicate msgid "Hello"`, err.Error())
+	}
+
+	invalid := buildMO(binary.LittleEndian, map[string]string{
+		"":                     "Plural-Forms: nplurals=2; plural=(n > 1);\n",
+		"One file\x00%d files": "Un fichier",
+	})
+	_, err = NewParser().ParseMO(invalid)
+	assert.NoError(t, err, `invalid plural entries should be accepted by default`)
+	_, err = NewParser(WithStrictParsing(true)).ParseMO(invalid)
+	if assert.Error(t, err, `invalid plural entries should be rejected in strict mode`) {
+		_, ok := errors.Cause(err).(MultiError)
+		assert.True(t, ok, `error should be a MultiError`)
+	}
+}
+
+func TestContextKey(t *testing.T) {
+	assert.Equal(t, "Open", contextKey("", "Open"))
+	assert.Equal(t, "menu\x04Open", contextKey("menu", "Open"))
+
+	ctxt, id := splitContextKey(contextKey("menu", "Open"))
+	assert.Equal(t, "menu", ctxt)
+	assert.Equal(t, "Open", id)
+
+	ctxt, id = splitContextKey("Open")
+	assert.Equal(t, "", ctxt)
+	assert.Equal(t, "Open", id)
+
+	po, err := NewParser().ParseString(`
+msgid ""
+msgstr ""
+"Language: fr\n"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgctxt "menu"
+msgid "One item"
+msgid_plural "%d items"
+msgstr[0] "Un élément"
+msgstr[1] "%d éléments"
+`)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "Ouvrir", po.Get("menu\x04Open"), `combined keys should be looked up in the context`)
+	assert.Equal(t, "Close", po.Get("menu\x04Close"), `missing entries should fall back to the msgid`)
+	assert.Equal(t, "3 éléments", po.GetN("menu\x04One item", "%d items", 3, 3))
+	assert.Equal(t, "apples", po.GetN("menu\x04apple", "apples", 1), `missing plural entries should fall back to the msgid_plural`)
+	assert.Equal(t, "Un élément", po.GetCategory("menu\x04One item", PluralOne))
+	assert.Equal(t, "Close", po.GetCategory("menu\x04Close", PluralOne), `missing entries should fall back to the msgid`)
+
+	m, ok := po.Message("menu\x04Open")
+	if assert.True(t, ok, `Message should find combined keys`) {
+		assert.Equal(t, "menu", m.Context)
+		assert.Equal(t, "Open", m.ID)
+	}
+	pluralID, ok := po.PluralID("menu\x04One item")
+	assert.True(t, ok, `PluralID should find combined keys`)
+	assert.Equal(t, "%d items", pluralID)
+
+	var missing []string
+	po.missingHandler = func(id string) string {
+		missing = append(missing, id)
+		return id
+	}
+	assert.Equal(t, "apple", po.GetN("menu\x04apple", "apples", 1), `missing handler should get the msgid`)
+	assert.Equal(t, []string{"apple"}, missing, `missing handler should not get the context`)
+}
+
+func TestWriteMO(t *testing.T) {
+	str := `msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgctxt "button"
+msgid "Open"
+msgstr "Ouvre"
+
+msgid "Untranslated"
+msgstr ""
+
+#, fuzzy, c-format
+msgid "Fuzzy"
+msgstr "Flou"
+
+#~ msgid "Obsolete"
+#~ msgstr "Obsolète"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, po.WriteMO(&buf), `WriteMO should succeed`) {
+		return
+	}
+
+	mo, err := NewParser(WithStrictParsing(true)).ParseMO(buf.Bytes())
+	if !assert.NoError(t, err, `ParseMO should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "fr", mo.Language())
+	assert.Equal(t, "Bonjour", mo.Get("Hello"))
+	assert.Equal(t, "Un fichier", mo.GetN("One file", "%d files", 1))
+	assert.Equal(t, "2 fichiers", mo.GetN("One file", "%d files", 2, 2))
+	assert.Equal(t, "Ouvrir", mo.GetC("Open", "menu"))
+	assert.Equal(t, "Ouvre", mo.GetC("Open", "button"))
+	assert.Equal(t, "Open", mo.Get("Open"), `contextual entries should not be registered without context`)
+
+	_, ok := mo.Message("Untranslated")
+	assert.False(t, ok, `untranslated entries should be left out`)
+	_, ok = mo.Message("Fuzzy")
+	assert.False(t, ok, `fuzzy entries should be left out`)
+	_, ok = mo.Message("Obsolete")
+	assert.False(t, ok, `obsolete entries should be left out`)
+
+	var again bytes.Buffer
+	if !assert.NoError(t, mo.WriteMO(&again), `WriteMO should succeed`) {
+		return
+	}
+	assert.Equal(t, buf.Bytes(), again.Bytes(), `output should be stable`)
+}
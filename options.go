@@ -1,10 +1,12 @@
 package gettext
 
+import "time"
+
 // WithSource is used in NewLocale() to specify where to load
 // the .po files from. By default FileSystemSource will be used.
 func WithSource(s Source) Option {
 	return &option{
-		name: "source",
+		name:  "source",
 		value: s,
 	}
 }
@@ -13,7 +15,195 @@ func WithSource(s Source) Option {
 // name of the domain that will be used by the `Get` method
 func WithDefaultDomain(s string) Option {
 	return &option{
-		name: "default_domain",
+		name:  "default_domain",
 		value: s,
 	}
 }
+
+// WithPollInterval is used in NewWatchingFileSystemSource() to specify
+// how often the directory is checked for changes. By default the
+// directory is checked every second. Durations that are not positive
+// are ignored.
+func WithPollInterval(d time.Duration) Option {
+	return &option{
+		name:  "poll_interval",
+		value: d,
+	}
+}
+
+// WithNamedPlaceholders is used in NewParser() and NewLocale() to
+// specify that translations use named placeholders such as %{name}
+// instead of the fmt.Printf syntax. In this mode the values are passed
+// as a single map[string]interface{} argument:
+//
+//	l.Get("Hello, %{name}", map[string]interface{}{"name": "John"})
+//
+// Named placeholders allow translators to reorder the values freely.
+// Without this option, values can also be reordered using explicit
+// argument indexes such as "%[2]d", which is usually simpler.
+func WithNamedPlaceholders(b bool) Option {
+	return &option{
+		name:  "named_placeholders",
+		value: b,
+	}
+}
+
+// WithSafeFormat is used in NewParser() and NewLocale() to guard against
+// translations whose fmt verbs do not match those of the source string.
+// When enabled, if formatting a translation produces errors such as
+// "%!d(string=...)" while formatting the source string does not, the
+// source string is used instead, so that garbled text is never shown.
+func WithSafeFormat(b bool) Option {
+	return &option{
+		name:  "safe_format",
+		value: b,
+	}
+}
+
+// WithFormatMismatchHandler is used in NewParser() and NewLocale() along
+// with WithSafeFormat. The given function is called with the source
+// string and the offending translation whenever the source string is
+// used in place of the translation, so that the mismatch can be logged.
+func WithFormatMismatchHandler(h func(msgid, msgstr string)) Option {
+	return &option{
+		name:  "format_mismatch_handler",
+		value: h,
+	}
+}
+
+// WithMissingHandler is used in NewParser() and NewLocale() to customize
+// what is returned when a catalog has no translation for a string. The
+// given function is called with the msgid, and its return value is used
+// verbatim, without formatting. This is useful for catalogs whose msgids
+// are symbolic keys, e.g. to return a marker such as "«missing: key»".
+//
+// By default, the formatted source string is returned.
+func WithMissingHandler(h func(msgid string) string) Option {
+	return &option{
+		name:  "missing_handler",
+		value: h,
+	}
+}
+
+// WithCLDRPlurals is used in NewParser() and NewLocale() to select plural
+// forms using the CLDR plural rules for the language in the Language
+// header, instead of evaluating the Plural-Forms formula. The msgstr[n]
+// forms must be ordered by CLDR category (zero, one, two, few, many,
+// other), omitting the categories that the language does not use.
+//
+// If the language is not known, the Plural-Forms formula is used.
+func WithCLDRPlurals(b bool) Option {
+	return &option{
+		name:  "cldr_plurals",
+		value: b,
+	}
+}
+
+// WithContextFallback is used in NewParser() and NewLocale() to make
+// lookups in a context (GetC, GetNC and friends) fall back to the
+// translation without context when the context has no entry for the
+// given string. This is useful when migrating a catalog to contexts
+// incrementally.
+//
+// The precedence is: the entry in the given context, then the entry
+// without context, then the source string.
+func WithContextFallback(b bool) Option {
+	return &option{
+		name:  "context_fallback",
+		value: b,
+	}
+}
+
+// WithTrimSpace is used in NewParser() and NewLocale() to remove the
+// spaces and tabs at the beginning and at the end of each msgstr. Spaces
+// inside of the strings are left untouched, and so are newlines, as they
+// are expected to match those of the msgid.
+func WithTrimSpace(b bool) Option {
+	return &option{
+		name:  "trim_space",
+		value: b,
+	}
+}
+
+// WithParser is used in NewLocale() to specify the Parser that is used
+// to parse the catalogs of each domain. If not specified, a Parser is
+// created with NewParser(), using the options that were passed to
+// NewLocale().
+func WithParser(p *Parser) Option {
+	return &option{
+		name:  "parser",
+		value: p,
+	}
+}
+
+// WithBaseLocale is used in NewLocale() for catalogs whose msgids are
+// symbolic keys rather than text in the source language. When a string
+// has no translation in the locale, it is looked up in the same domain
+// of the base locale (usually the one holding the source language text)
+// instead of returning the key. The base locale must not be the locale
+// itself.
+//
+// The base locale is consulted by all of the lookup methods, with or
+// without a context, such as Get, GetN, GetC, GetNDC, TryGetD, GetRaw and
+// the Fprintf family.
+func WithBaseLocale(l Locale) Option {
+	return &option{
+		name:  "base_locale",
+		value: l,
+	}
+}
+
+// WithLazyDomains is used in NewLocale() to load domains on first use,
+// instead of requiring AddDomain to be called beforehand. The first
+// lookup in a domain that has not been loaded (through GetD, GetND and
+// friends, or Domain) loads it from the Source. The goroutines that use
+// the domain at the same time share a single loading. If it fails, for
+// example because there is no catalog for the domain, the lookup returns
+// the source string, and the loading is attempted again by the next
+// lookup. GetDErr reports the error of the loading.
+func WithLazyDomains(b bool) Option {
+	return &option{
+		name:  "lazy_domains",
+		value: b,
+	}
+}
+
+// WithIgnoreMissingDomains is used in LocaleSet.Options() to make
+// LocaleSet.AddLocale skip the domains that have no catalog for the
+// locale, instead of failing. The skipped domains can be retrieved
+// with LocaleSet.MissingDomains(). Catalogs that exist but fail to
+// parse are still reported as errors.
+func WithIgnoreMissingDomains(b bool) Option {
+	return &option{
+		name:  "ignore_missing_domains",
+		value: b,
+	}
+}
+
+// WithLayout is used in NewLocale() to specify where the catalogs are
+// looked for under the root of the source. Each template is a slash
+// separated path that may contain the following placeholders:
+//
+//	{lang}      the normalized language name, e.g. "pt_BR"
+//	{language}  the language code alone, e.g. "pt"
+//	{domain}    the name of the domain
+//
+// If the language name has a charset or a modifier, such as
+// "de_DE.UTF-8" or "sr_RS@latin", {lang} is tried with the full name,
+// then without the charset, then without the modifier, and {language}
+// is tried with the modifier ("sr@latin") before the language code alone.
+//
+// The templates are tried in order. If a template does not end in
+// ".po" or ".mo", the .mo file is tried first, then the .po file. The
+// default layout is:
+//
+//	{lang}/LC_MESSAGES/{domain}
+//	{language}/LC_MESSAGES/{domain}
+//	{lang}/{domain}
+//	{language}/{domain}
+func WithLayout(templates ...string) Option {
+	return &option{
+		name:  "layout",
+		value: templates,
+	}
+}
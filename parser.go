@@ -4,12 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/textproto"
 	"strconv"
 	"strings"
 
-	"github.com/mattn/kinako/parser"
 	"github.com/pkg/errors"
 )
 
@@ -21,6 +22,12 @@ func (o option) Value() interface{} {
 	return o.value
 }
 
+// WithStrictParsing is used in NewParser() and NewLocale() to make
+// parsing fail on malformed catalogs, instead of silently skipping the
+// offending lines. Entries that are defined more than once are also
+// reported as errors (see Po.Duplicates), and so are the problems found
+// by Po.Validate, all at once as a MultiError. When passed to NewLocale,
+// AddDomain returns the parse error.
 func WithStrictParsing(b bool) Option {
 	return &option{
 		name:  "strict",
@@ -29,19 +36,69 @@ func WithStrictParsing(b bool) Option {
 }
 
 // NewParser creates a new .po parser
+//
+// Possible options include:
+// * WithStrictParsing: fail on malformed content instead of skipping it
+// * WithNamedPlaceholders: use %{name} placeholders instead of fmt.Printf syntax
+// * WithSafeFormat: fall back to the source string when a translation can't be formatted
+// * WithFormatMismatchHandler: callback invoked when WithSafeFormat falls back
+// * WithCLDRPlurals: use CLDR plural rules instead of the Plural-Forms header
+// * WithContextFallback: use translations without context when a context has no entry
+// * WithTrimSpace: remove leading and trailing spaces from translations
+// * WithMissingHandler: callback that provides the result for missing translations
+//
+// Options that are not recognized are ignored.
 func NewParser(options ...Option) *Parser {
 	var strict bool
+	var namedPlaceholders bool
+	var safeFormat bool
+	var formatMismatchHandler func(string, string)
+	var cldrPlurals bool
+	var contextFallback bool
+	var trimSpace bool
+	var missingHandler func(string) string
 	for _, o := range options {
 		switch o.Name() {
+		case "missing_handler":
+			missingHandler = o.Value().(func(string) string)
+		case "trim_space":
+			trimSpace = o.Value().(bool)
+		case "context_fallback":
+			contextFallback = o.Value().(bool)
+		case "cldr_plurals":
+			cldrPlurals = o.Value().(bool)
 		case "strict":
 			strict = o.Value().(bool)
+		case "named_placeholders":
+			namedPlaceholders = o.Value().(bool)
+		case "safe_format":
+			safeFormat = o.Value().(bool)
+		case "format_mismatch_handler":
+			formatMismatchHandler = o.Value().(func(string, string))
 		}
 	}
 	return &Parser{
-		strict: strict,
+		cldrPlurals:           cldrPlurals,
+		contextFallback:       contextFallback,
+		formatMismatchHandler: formatMismatchHandler,
+		missingHandler:        missingHandler,
+		namedPlaceholders:     namedPlaceholders,
+		safeFormat:            safeFormat,
+		strict:                strict,
+		trimSpace:             trimSpace,
 	}
 }
 
+// maxPluralForms is the number of msgstr[n] forms that the parser
+// accepts for an entry, far more than any language needs. The Plural-Forms
+// header cannot be used instead, as it is only parsed at the end
+const maxPluralForms = 100
+
+// maxLineSize is the longest line that ParseReader accepts, as the
+// scanner that reads the lines needs a limit. Parse, which has all of the
+// data at hand, accepts lines of any length
+const maxLineSize = 1024 * 1024
+
 func (p *Parser) ParseFile(f string) (*Po, error) {
 	data, err := ioutil.ReadFile(f)
 	if err != nil {
@@ -55,13 +112,73 @@ func (p *Parser) ParseString(s string) (*Po, error) {
 }
 
 func (p *Parser) Parse(data []byte) (*Po, error) {
-	var ctx parseCtx
-	ctx.Context = context.Background()
-	ctx.strict = p.strict
-	ctx.po = newPo()
-	ctx.buf = data
-	ctx.curTranslation = newTranslation()
-	if err := ctx.Run(ctx); err != nil {
+	return p.ParseContext(context.Background(), data)
+}
+
+// ParseContext is like Parse, but stops parsing when the given context
+// is canceled. In that case the error returned by ctx.Err() is returned,
+// regardless of the strict mode.
+func (p *Parser) ParseContext(ctx context.Context, data []byte) (*Po, error) {
+	pc := p.newParseCtx(ctx, countEntries(data))
+	// The data is converted to a string once, and each line is sliced
+	// out of it, so that reading lines does not allocate
+	pc.data = string(data)
+	return p.parse(pc)
+}
+
+// ParseReader parses the catalog read from r. Unlike Parse, the input
+// is processed line by line, and is never held in memory as a whole.
+// Because of this, lines of 1 MiB or more are reported as errors.
+func (p *Parser) ParseReader(r io.Reader) (*Po, error) {
+	pc := p.newParseCtx(context.Background(), 0)
+	pc.scanner = bufio.NewScanner(r)
+	pc.scanner.Buffer(nil, maxLineSize)
+	pc.scanner.Split(scanLines)
+	return p.parse(pc)
+}
+
+// newPo creates an empty Po object, with room for n messages, that is
+// configured with the options of the parser
+func (p *Parser) newPo(n int) *Po {
+	po := newPo(n)
+	po.namedPlaceholders = p.namedPlaceholders
+	po.safeFormat = p.safeFormat
+	po.formatMismatchHandler = p.formatMismatchHandler
+	po.missingHandler = p.missingHandler
+	po.cldrPlurals = p.cldrPlurals
+	po.contextFallback = p.contextFallback
+	return po
+}
+
+// newParseCtx creates the state for parsing a catalog. n is the
+// estimated number of messages in the catalog, if known. The caller
+// must set up the source of the lines
+func (p *Parser) newParseCtx(cctx context.Context, n int) *parseCtx {
+	return &parseCtx{
+		Context:        cctx,
+		strict:         p.strict,
+		trimSpace:      p.trimSpace,
+		po:             p.newPo(n),
+		curTranslation: newTranslation(),
+	}
+}
+
+// parse parses the catalog that ctx reads from
+func (p *Parser) parse(ctx *parseCtx) (*Po, error) {
+	err := ctx.Run(ctx)
+
+	// Cancellation is always reported, as the catalog is incomplete
+	if cerr := ctx.Err(); cerr != nil {
+		return nil, cerr
+	}
+
+	// Errors while reading are always reported, regardless of the
+	// strict mode
+	if rerr := ctx.readErr(); rerr != nil {
+		return nil, errors.Wrap(rerr, `po: failed to read`)
+	}
+
+	if err != nil {
 		if p.strict {
 			return nil, errors.Wrap(err, `po: failed to parse`)
 		}
@@ -69,83 +186,250 @@ func (p *Parser) Parse(data []byte) (*Po, error) {
 	return ctx.po, nil
 }
 
-func (p *parseCtx) Next() bool {
-	return p.pos < len(p.buf)
+// countEntries makes a cheap estimate of the number of messages without
+// a context in the given catalog, so that the map can be allocated up
+// front. Obsolete entries are counted too, so the estimate may be
+// slightly too large.
+func countEntries(data []byte) int {
+	var ids, ctxs int
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexAny(data, "\r\n"); i > -1 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+
+		line = bytes.TrimLeft(line, " \t#~")
+		switch {
+		case bytes.HasPrefix(line, []byte("msgid ")):
+			ids++
+		case bytes.HasPrefix(line, []byte("msgctxt ")):
+			ctxs++
+		}
+	}
+
+	if ctxs > ids {
+		return 0
+	}
+	return ids - ctxs
 }
 
-func (p *parseCtx) Line() string {
-	oldpos := p.pos
-	i := bytes.IndexByte(p.buf[oldpos:], '\n')
-	if i == oldpos {
-		p.pos++
-		return ""
+// scanLines is a bufio.SplitFunc like bufio.ScanLines, but it accepts
+// "\r\n", "\n" and a lone "\r" as line endings, so that catalogs
+// edited on any platform are parsed the same way
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i > -1 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+
+		// "\r". We need to see the next byte to know if it is "\r\n"
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func (e *ParseError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf(`po: line %d: %s`, e.Line, e.Message)
+	}
+	return fmt.Sprintf(`po: line %d: %s: %s`, e.Line, e.Message, e.err)
+}
+
+// Unwrap returns the underlying error, if any
+func (e *ParseError) Unwrap() error {
+	return e.err
+}
+
+func (p *parseCtx) parseError(err error, msg string) error {
+	return &ParseError{Line: p.line, Message: msg, err: err}
+}
+
+// Next advances to the next line, which is then available through Line
+func (p *parseCtx) Next() bool {
+	if p.scanner != nil {
+		if !p.scanner.Scan() {
+			return false
+		}
+		p.text = p.scanner.Text()
+		return true
+	}
+
+	if p.data == "" {
+		return false
+	}
+
+	// Same rules as scanLines
+	line, rest := p.data, ""
+	if i := strings.IndexAny(p.data, "\r\n"); i > -1 {
+		line, rest = p.data[:i], p.data[i+1:]
+		if p.data[i] == '\r' && strings.HasPrefix(rest, "\n") {
+			rest = rest[1:]
+		}
 	}
+	p.data = rest
+	p.text = line
+	return true
+}
 
-	if i == -1 {
-		p.pos = len(p.buf)
-		return string(p.buf[oldpos:])
+// readErr returns the error that stopped reading lines, if any
+func (p *parseCtx) readErr() error {
+	if p.scanner != nil {
+		return p.scanner.Err()
 	}
+	return nil
+}
 
-	p.pos += i + 1
-	return string(p.buf[oldpos : oldpos+i])
+// bom is the UTF-8 byte order mark, which some editors put at the
+// beginning of files
+const bom = "\xef\xbb\xbf"
+
+func (p *parseCtx) Line() string {
+	p.line++
+	if p.line == 1 {
+		return strings.TrimPrefix(p.text, bom)
+	}
+	return p.text
 }
 
+// cancelCheckInterval is the number of lines between checks for the
+// cancellation of the context while parsing
+const cancelCheckInterval = 1024
+
 func (p *parseCtx) Run(ctx context.Context) error {
 	const (
 		msgid       = `msgid`
 		msgidPlural = `msgid_plural`
 		msgstr      = `msgstr`
 		msgctxt     = `msgctxt`
+		obsolete    = `#~`
+		previous    = `#|`
 	)
 
 	for p.Next() {
+		// Checking for cancellation on every line would be wasteful
+		if p.line%cancelCheckInterval == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+		}
+
 		l := strings.TrimSpace(p.Line())
 
+		// Obsolete entries are parsed just like regular entries,
+		// but they are stored separately from the translations
+		p.obsolete = strings.HasPrefix(l, obsolete)
+		if p.obsolete {
+			l = strings.TrimSpace(l[len(obsolete):])
+			// Previous msgid of an obsolete entry ("#~|")
+			if strings.HasPrefix(l, "|") {
+				l = "#" + l
+			}
+		}
+
 		switch {
 		case strings.HasPrefix(l, msgctxt):
 			if err := p.parseContext(l[len(msgctxt):]); err != nil {
 				if !p.strict {
 					continue
 				}
-				return errors.Wrap(err, `po: failed to parse msgctxt`)
+				return p.parseError(err, `failed to parse msgctxt`)
 			}
 		case strings.HasPrefix(l, msgidPlural):
 			if err := p.parsePluralID(l[len(msgidPlural):]); err != nil {
 				if !p.strict {
 					continue
 				}
-				return errors.Wrap(err, `po: failed to parse msgid_plural`)
+				return p.parseError(err, `failed to parse msgid_plural`)
 			}
 		case strings.HasPrefix(l, msgid):
 			if err := p.parseID(l[len(msgid):]); err != nil {
 				if !p.strict {
 					continue
 				}
-				return errors.Wrap(err, `po: failed to parse msgid`)
+				return p.parseError(err, `failed to parse msgid`)
 			}
 		case strings.HasPrefix(l, msgstr):
 			if err := p.parseMessage(l[len(msgstr):]); err != nil {
 				if !p.strict {
 					continue
 				}
-				return errors.Wrap(err, `po: failed to parse msgstr`)
+				return p.parseError(err, `failed to parse msgstr`)
 			}
-		// Multi line strings and headers
-		case strings.HasPrefix(l, "\"") && strings.HasSuffix(l, "\""):
+		// Multi line strings and headers. Unterminated strings are
+		// passed along as well, so that they are reported as errors
+		case strings.HasPrefix(l, "\""):
 			if err := p.parseString(l); err != nil {
 				if !p.strict {
 					continue
 				}
-				return errors.Wrap(err, `po: failed to parse header/multi-line string`)
+				return p.parseError(err, `failed to parse header/multi-line string`)
+			}
+		case strings.HasPrefix(l, previous):
+			p.addComment(l)
+			if err := p.parsePrevious(l[len(previous):]); err != nil {
+				if !p.strict {
+					continue
+				}
+				return p.parseError(err, `failed to parse previous msgid`)
+			}
+		// Blank lines and comments
+		case l == "":
+		case strings.HasPrefix(l, "#"):
+			p.addComment(l)
+		default:
+			if p.strict {
+				return p.parseError(nil, `unexpected content`)
 			}
 		}
+
+		if p.dupErr != nil {
+			return p.dupErr
+		}
 	}
 
 	p.pop()
+	if p.dupErr != nil {
+		return p.dupErr
+	}
 
 	if err := p.parseHeaders(); err != nil {
 		if p.strict {
-			return errors.Wrap(err, `po: failed to parse header`)
+			return &ParseError{Line: p.headerLine, Message: `failed to parse header`, err: err}
+		}
+	}
+
+	// Everything is converted to UTF-8, so that catalogs in different
+	// charsets can be used side by side
+	if err := p.convertCharset(); err != nil {
+		if p.strict {
+			return &ParseError{Line: p.headerLine, Message: `failed to convert charset`, err: err}
+		}
+	}
+
+	if p.strict {
+		if errs := p.po.Validate(); len(errs) > 0 {
+			return MultiError(errs)
 		}
 	}
 
@@ -157,43 +441,79 @@ func (p *parseCtx) pop() {
 	curC := p.curContext
 
 	p.curTranslation = newTranslation()
+	p.curIndex = 0
+	p.curField = fieldNone
 
 	if curT.id == "" {
 		return
 	}
 
+	if p.trimSpace {
+		for i := range curT.Trs {
+			curT.Trs[i].text = strings.Trim(curT.Trs[i].text, " \t")
+		}
+	}
+
 	p.curContext = ""
+	curT.ctx = curC
 
-	if curC == "" {
-		p.po.translations[curT.id] = curT
+	if curT.obsolete {
+		p.po.obsolete = append(p.po.obsolete, curT)
 		return
 	}
 
-	if _, ok := p.po.contexts[curC]; !ok {
-		p.po.contexts[curC] = make(map[string]*translation)
+	m := p.po.translations
+	if curC != "" {
+		if m = p.po.contexts[curC]; m == nil {
+			m = make(map[string]*translation)
+			p.po.contexts[curC] = m
+		}
 	}
-	p.po.contexts[curC][curT.id] = curT
+
+	// The last occurrence of a duplicate entry wins, as it always did,
+	// but the duplicate is recorded so that tools can warn about it
+	if _, ok := m[curT.id]; ok {
+		key := contextKey(curC, curT.id)
+		p.po.duplicates = append(p.po.duplicates, key)
+		if p.strict && p.dupErr == nil {
+			p.dupErr = &ParseError{Line: p.curLine, Message: `duplicate msgid ` + strconv.Quote(key)}
+		}
+	}
+	m[curT.id] = curT
 }
 
+// Fields of an entry that may be continued on the following lines
+const (
+	fieldNone = iota
+	fieldContext
+	fieldID
+	fieldPluralID
+	fieldMessage
+)
+
 func (p *parseCtx) parseContext(l string) error {
 	p.pop()
 
 	// Buffer context
-	txt, err := strconv.Unquote(strings.TrimSpace(l))
+	txt, err := unquote(strings.TrimSpace(l))
 	if err != nil {
 		return errors.Wrap(err, `po: failed to unquote msgctx`)
 	}
 
 	p.curContext = txt
+	p.curLine = p.line
+	p.curField = fieldContext
+	p.curTranslation.obsolete = p.obsolete
 	return nil
 }
 
 func (p *parseCtx) parsePluralID(l string) error {
-	txt, err := strconv.Unquote(strings.TrimSpace(l))
+	txt, err := unquote(strings.TrimSpace(l))
 	if err != nil {
 		return errors.Wrap(err, `po: failed to unquote plural ID`)
 	}
 	p.curTranslation.PluralID = txt
+	p.curField = fieldPluralID
 	return nil
 }
 
@@ -201,11 +521,91 @@ func (p *parseCtx) parseID(s string) error {
 	p.pop()
 
 	// Set id
-	id, err := strconv.Unquote(strings.TrimSpace(s))
+	id, err := unquote(strings.TrimSpace(s))
 	if err != nil {
 		return errors.Wrapf(err, `po: failed to parse ID (%s)`, strconv.Quote(s))
 	}
 	p.curTranslation.id = id
+	if p.curContext == "" {
+		p.curLine = p.line
+	}
+	p.curField = fieldID
+	p.curTranslation.obsolete = p.obsolete
+
+	// Attach the "#|" markers that preceded this entry
+	p.curTranslation.PreviousContext = p.prevContext
+	p.curTranslation.PreviousID = p.prevID
+	p.curTranslation.PreviousPluralID = p.prevPluralID
+	p.prevContext, p.prevID, p.prevPluralID = "", "", ""
+	p.prevField = nil
+	p.curTranslation.Comments = p.comments
+	p.comments = nil
+	return nil
+}
+
+// addComment records the comment line l for the upcoming entry. For
+// obsolete entries, l must not include the "#~" prefix
+func (p *parseCtx) addComment(l string) {
+	c := Comment{Type: CommentTranslator}
+	text := l[1:]
+	if len(text) > 0 {
+		switch text[0] {
+		case '.':
+			c.Type = CommentExtracted
+		case ':':
+			c.Type = CommentReference
+		case ',':
+			c.Type = CommentFlag
+		case '|':
+			if p.obsolete {
+				c.Type = CommentObsolete
+			} else {
+				c.Type = CommentPrevious
+			}
+		}
+		if c.Type != CommentTranslator {
+			text = text[1:]
+		}
+	}
+	c.Text = strings.TrimPrefix(text, " ")
+	p.comments = append(p.comments, c)
+}
+
+// parsePrevious parses the content of "#|" lines, which record the
+// msgctxt/msgid/msgid_plural of an entry before it was marked fuzzy
+// by msgmerge
+func (p *parseCtx) parsePrevious(l string) error {
+	l = strings.TrimSpace(l)
+
+	var field *string
+	switch {
+	case strings.HasPrefix(l, `msgctxt`):
+		field, l = &p.prevContext, l[len(`msgctxt`):]
+	case strings.HasPrefix(l, `msgid_plural`):
+		field, l = &p.prevPluralID, l[len(`msgid_plural`):]
+	case strings.HasPrefix(l, `msgid`):
+		field, l = &p.prevID, l[len(`msgid`):]
+	case strings.HasPrefix(l, `"`):
+		// Continuation of the previous field
+		if p.prevField == nil {
+			return errors.New(`po: multi-line string without preceding field`)
+		}
+		txt, err := unquote(l)
+		if err != nil {
+			return errors.Wrap(err, `po: failed to unquote previous string`)
+		}
+		*p.prevField += txt
+		return nil
+	default:
+		return errors.New(`po: unknown previous field`)
+	}
+
+	txt, err := unquote(strings.TrimSpace(l))
+	if err != nil {
+		return errors.Wrap(err, `po: failed to unquote previous string`)
+	}
+	*field = txt
+	p.prevField = field
 	return nil
 }
 
@@ -215,15 +615,15 @@ func (p *parseCtx) parseMessage(l string) error {
 	// Check for indexed translation forms
 	if !strings.HasPrefix(l, "[") {
 		// Save single translation form under 0 index
-		txt, err := strconv.Unquote(l)
+		txt, err := unquote(l)
 		if err != nil {
 			return errors.Wrap(err, `po: failed to unquote msgstr`)
 		}
 
-		// XXX This is silly. We should just use a slice
 		p.curTranslation.Trs.Set(0, txt)
+		p.curIndex = 0
+		p.curField = fieldMessage
 		return nil
-
 	}
 
 	idx := strings.Index(l, "]")
@@ -239,45 +639,76 @@ func (p *parseCtx) parseMessage(l string) error {
 		return errors.Wrap(err, `po: failed to parse index`)
 	}
 
+	if i < 0 {
+		return errors.Errorf(`po: invalid negative index %d`, i)
+	}
+	// The forms are stored in a slice, so a huge index would make it
+	// allocate all of the forms up to the index
+	if i >= maxPluralForms {
+		return errors.Errorf(`po: index %d is out of range`, i)
+	}
+
 	// Parse translation string
-	txt, err := strconv.Unquote(strings.TrimSpace(l[idx+1:]))
+	txt, err := unquote(strings.TrimSpace(l[idx+1:]))
 	if err != nil {
 		return errors.Wrapf(err, `po: failed to unquote msgstr[%d]`, i)
 	}
 
 	p.curTranslation.Trs.Set(i, txt)
+	p.curIndex = i
+	p.curField = fieldMessage
 	return nil
 }
 
 func (p *parseCtx) parseString(l string) error {
-	// Check for multiline from previously set msgid
-	if p.curTranslation.id != "" {
-		// Append to last translation found
-		uq, err := strconv.Unquote(l)
+	// The msgstr of the entry with an empty msgid holds the headers.
+	// Strings without a preceding field are treated as headers too
+	header := p.curField == fieldNone || (p.curField == fieldMessage && p.curTranslation.id == "")
+	if !header {
+		uq, err := unquote(l)
 		if err != nil {
 			return errors.Wrap(err, `po: failed to unquote multi-line string`)
 		}
 
-		lastidx := p.curTranslation.Trs.Len() - 1
-		v, ok := p.curTranslation.Trs.Get(lastidx)
-		if ok { // sanity
-			p.curTranslation.Trs.Set(lastidx, v+uq)
+		// Continuation lines extend the field that was most recently set.
+		// For msgstr, it is not necessarily the one with the largest index
+		switch p.curField {
+		case fieldContext:
+			p.curContext += uq
+		case fieldID:
+			p.curTranslation.id += uq
+		case fieldPluralID:
+			p.curTranslation.PluralID += uq
+		case fieldMessage:
+			v, ok := p.curTranslation.Trs.Get(p.curIndex)
+			if ok { // sanity
+				p.curTranslation.Trs.Set(p.curIndex, v+uq)
+			}
 		}
+		return nil
+	}
 
+	// Obsolete entries never contain headers
+	if p.obsolete {
 		return nil
 	}
 
 	// Otherwise is a header
-	h, err := strconv.Unquote(strings.TrimSpace(l))
+	h, err := unquote(strings.TrimSpace(l))
 	if err != nil {
 		return errors.Wrap(err, `po: failed to unquote header`)
 	}
 
+	if p.headerLine == 0 {
+		p.headerLine = p.line
+	}
 	p.rawHeaders += h
 	return nil
 }
 
 func (p *parseCtx) parseHeaders() error {
+	p.po.rawHeader = p.rawHeaders
+
 	// Make sure we end with 2 carriage returns.
 	p.rawHeaders += "\n\n"
 
@@ -294,33 +725,144 @@ func (p *parseCtx) parseHeaders() error {
 	p.po.language = mimeHeader.Get("Language")
 	p.po.pluralForms = mimeHeader.Get("Plural-Forms")
 
-	// Parse Plural-Forms formula
-	if p.po.pluralForms == "" {
-		return nil
+	// Keep all of the headers in order, so that they can be written back
+	for _, line := range strings.Split(p.rawHeaders, "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		p.po.headers = append(p.po.headers, header{
+			name:  strings.TrimSpace(line[:i]),
+			value: strings.TrimSpace(line[i+1:]),
+		})
 	}
 
-	// Split plural form header value
-	pfs := strings.Split(p.po.pluralForms, ";")
+	// Parse Plural-Forms formula. If the header is missing, use the
+	// default for the language of the catalog
+	pluralForms := p.po.pluralForms
+	if pluralForms == "" {
+		pluralForms = lookupPluralForms(p.po.language)
+	}
+	return p.po.parsePluralForms(pluralForms)
+}
 
-	// Parse values
-	for _, i := range pfs {
-		vs := strings.SplitN(i, "=", 2)
-		if len(vs) != 2 {
+// unquote interprets s as a double-quoted PO string, and returns the
+// string value that s quotes.
+//
+// strconv.Unquote is not used because its rules are subtly different
+// from those of GNU gettext. For example, it rejects escape sequences
+// such as `\e`, which can be found in real-world catalogs.
+func unquote(s string) (string, error) {
+	n := len(s)
+	if n < 2 || s[0] != '"' || s[n-1] != '"' {
+		return "", errors.New(`po: string is not enclosed in double quotes`)
+	}
+	s = s[1 : n-1]
+
+	// Fast path: nothing to unescape
+	if strings.IndexByte(s, '\\') == -1 {
+		if strings.IndexByte(s, '"') != -1 {
+			return "", errors.New(`po: unescaped double quote in string`)
+		}
+		return s, nil
+	}
+
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			return "", errors.New(`po: unescaped double quote in string`)
+		case '\\':
+		default:
+			buf = append(buf, c)
 			continue
 		}
 
-		switch strings.TrimSpace(vs[0]) {
-		case "nplurals":
-			p.po.nplurals, _ = strconv.Atoi(vs[1])
+		i++
+		if i >= len(s) {
+			return "", errors.New(`po: string ends with a backslash`)
+		}
 
-		case "plural":
-			// compile this now
-			stmts, err := parser.ParseSrc(vs[1])
+		switch c = s[i]; c {
+		case 'n':
+			buf = append(buf, '\n')
+		case 't':
+			buf = append(buf, '\t')
+		case 'r':
+			buf = append(buf, '\r')
+		case 'a':
+			buf = append(buf, '\a')
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'v':
+			buf = append(buf, '\v')
+		case 'e':
+			buf = append(buf, '\x1b')
+		case '"', '\\', '\'', '?':
+			buf = append(buf, c)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// up to 3 octal digits
+			v := 0
+			j := i
+			for ; j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7'; j++ {
+				v = v*8 + int(s[j]-'0')
+			}
+			if v > 0xff {
+				return "", errors.Errorf(`po: octal escape \%s is out of range`, s[i:j])
+			}
+			buf = append(buf, byte(v))
+			i = j - 1
+		case 'x':
+			// up to 2 hex digits
+			v := 0
+			j := i + 1
+			for ; j < len(s) && j < i+3; j++ {
+				d, ok := hexDigit(s[j])
+				if !ok {
+					break
+				}
+				v = v*16 + d
+			}
+			if j == i+1 {
+				return "", errors.New(`po: \x used with no following hex digits`)
+			}
+			buf = append(buf, byte(v))
+			i = j - 1
+		case 'u', 'U':
+			// Not part of the gettext escape set, but accepted by earlier
+			// versions of this package, which used strconv.Unquote
+			size := 4
+			if c == 'U' {
+				size = 8
+			}
+			if i+size >= len(s) {
+				return "", errors.Errorf(`po: truncated \%c escape`, c)
+			}
+			v, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
 			if err != nil {
-				return errors.Wrap(err, `po: failed to parse plural form spec`)
+				return "", errors.Wrapf(err, `po: invalid \%c escape`, c)
 			}
-			p.po.plural = stmts
+			buf = append(buf, string(rune(v))...)
+			i += size
+		default:
+			return "", errors.Errorf(`po: invalid escape sequence \%c`, c)
 		}
 	}
-	return nil
+
+	return string(buf), nil
+}
+
+func hexDigit(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10, true
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
 }
@@ -0,0 +1,57 @@
+package gettext
+
+import "strings"
+
+// fallbackPluralForms is used when neither the Plural-Forms header nor
+// the language of the catalog is available, and matches the rule used
+// for English and the majority of the Germanic languages
+const fallbackPluralForms = `nplurals=2; plural=(n != 1);`
+
+// defaultPluralForms maps language codes to the Plural-Forms header values
+// that are commonly used for them. They are used for catalogs that do not
+// specify their own Plural-Forms header.
+var defaultPluralForms = map[string]string{
+	"ar":    `nplurals=6; plural=(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5);`,
+	"be":    `nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);`,
+	"bs":    `nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);`,
+	"cs":    `nplurals=3; plural=(n==1 ? 0 : n>=2 && n<=4 ? 1 : 2);`,
+	"cy":    `nplurals=4; plural=(n==1 ? 0 : n==2 ? 1 : n!=8 && n!=11 ? 2 : 3);`,
+	"fr":    `nplurals=2; plural=(n > 1);`,
+	"ga":    `nplurals=5; plural=(n==1 ? 0 : n==2 ? 1 : n>=3 && n<=6 ? 2 : n>=7 && n<=10 ? 3 : 4);`,
+	"hr":    `nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);`,
+	"is":    `nplurals=2; plural=(n%10!=1 || n%100==11);`,
+	"lt":    `nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && (n%100<10 || n%100>=20) ? 1 : 2);`,
+	"lv":    `nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n != 0 ? 1 : 2);`,
+	"mk":    `nplurals=2; plural=(n==1 || n%10==1 ? 0 : 1);`,
+	"pl":    `nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);`,
+	"pt_BR": `nplurals=2; plural=(n > 1);`,
+	"ro":    `nplurals=3; plural=(n==1 ? 0 : (n==0 || (n%100 > 0 && n%100 < 20)) ? 1 : 2);`,
+	"ru":    `nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);`,
+	"sk":    `nplurals=3; plural=(n==1 ? 0 : n>=2 && n<=4 ? 1 : 2);`,
+	"sl":    `nplurals=4; plural=(n%100==1 ? 0 : n%100==2 ? 1 : n%100==3 || n%100==4 ? 2 : 3);`,
+	"sr":    `nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);`,
+	"uk":    `nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);`,
+}
+
+func init() {
+	for _, lang := range []string{"id", "ja", "km", "ko", "lo", "ms", "my", "th", "vi", "zh"} {
+		defaultPluralForms[lang] = `nplurals=1; plural=0;`
+	}
+}
+
+// lookupPluralForms returns the default Plural-Forms header value for the
+// given language (e.g. "ru" or "pt_BR"). If the language is not known,
+// the English rule is returned.
+func lookupPluralForms(lang string) string {
+	lang = NormalizeLang(lang)
+	if v, ok := defaultPluralForms[lang]; ok {
+		return v
+	}
+
+	if i := strings.IndexAny(lang, "_.@"); i > -1 {
+		if v, ok := defaultPluralForms[lang[:i]]; ok {
+			return v
+		}
+	}
+	return fallbackPluralForms
+}
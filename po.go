@@ -1,38 +1,77 @@
 package gettext
 
-import "github.com/mattn/kinako/vm"
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
+	"github.com/mattn/kinako/parser"
+	"github.com/mattn/kinako/vm"
+	"github.com/pkg/errors"
+)
+
+// Len returns the number of slots in the list, including gaps
 func (l textlist) Len() int {
 	return len(l)
 }
 
+// Set stores s as the idx-th form. The list is grown as necessary,
+// and any slots that are created in between are left unset.
+// Negative indices are ignored
 func (l *textlist) Set(idx int, s string) {
-	if len(*l) <= idx {
-		newl := make([]string, idx+1)
+	if idx < 0 {
+		return
+	}
+
+	switch {
+	case idx < len(*l):
+	case idx < cap(*l):
+		// Lists never shrink, so the slots past the end are still unset
+		*l = (*l)[:idx+1]
+	default:
+		newl := make(textlist, idx+1)
 		copy(newl, *l)
 		*l = newl
 	}
 
-	(*l)[idx] = s
+	(*l)[idx] = textentry{text: s, valid: true}
 }
 
+// Get returns the idx-th form. The second return value is false if
+// idx is out of range, or if the form has not been set
 func (l textlist) Get(idx int) (string, bool) {
-	if len(l) <= idx {
+	if idx < 0 || len(l) <= idx {
+		return "", false
+	}
+	if !l[idx].valid {
 		return "", false
 	}
-	return l[idx], true
+	return l[idx].text, true
 }
 
 func newTranslation() *translation {
 	tr := &translation{}
-	tr.Trs = textlist(nil)
+	// Most entries have a single form, which is stored inline to save
+	// an allocation
+	tr.Trs = tr.single[:0]
 
 	return tr
 }
 
+// translated returns true if the n-th form has been translated. Empty
+// forms, which tools emit for untranslated entries, do not count
+func (t *translation) translated(n int) bool {
+	v, ok := t.Trs.Get(n)
+	return ok && v != ""
+}
+
 func (t *translation) get() string {
 	// Look for translation index 0
-	if v, ok := t.Trs.Get(0); ok {
+	if t.translated(0) {
+		v, _ := t.Trs.Get(0)
 		return v
 	}
 
@@ -42,24 +81,210 @@ func (t *translation) get() string {
 
 func (t *translation) getN(n int) (s string) {
 	// Look for translation index
-	if v, ok := t.Trs.Get(n); ok {
+	if t.translated(n) {
+		v, _ := t.Trs.Get(n)
 		return v
 	}
 
-	// Return unstranlated plural by default
+	// Return unstranlated plural by default, or the msgid for entries
+	// that have no plural
+	if t.PluralID == "" {
+		return t.id
+	}
 	return t.PluralID
 }
 
-func newPo() *Po {
+// fuzzy returns true if the entry has been marked with the fuzzy flag
+func (t *translation) fuzzy() bool {
+	for _, c := range t.Comments {
+		if c.Type != CommentFlag {
+			continue
+		}
+		for _, flag := range strings.Split(c.Text, ",") {
+			if strings.TrimSpace(flag) == "fuzzy" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *translation) message() Message {
+	strs := make([]string, t.Trs.Len())
+	for i := range strs {
+		strs[i], _ = t.Trs.Get(i)
+	}
+
+	return Message{
+		Context:          t.ctx,
+		ID:               t.id,
+		PluralID:         t.PluralID,
+		Strings:          strs,
+		PreviousContext:  t.PreviousContext,
+		PreviousID:       t.PreviousID,
+		PreviousPluralID: t.PreviousPluralID,
+		Comments:         append([]Comment(nil), t.Comments...),
+	}
+}
+
+// format formats the translated string according to the formatting
+// mode that the Po object was created with
+func (po *Po) format(str string, vars ...interface{}) string {
+	if po.namedPlaceholders {
+		return formatNamed(str, vars...)
+	}
+	return format(str, vars...)
+}
+
+// missing returns the result for a msgid that has no translation: the
+// formatted source string src, unless a missing handler has been set
+func (po *Po) missing(msgid, src string, vars ...interface{}) string {
+	if po.missingHandler != nil {
+		return po.missingHandler(msgid)
+	}
+	return po.format(src, vars...)
+}
+
+// translate returns the formatted translation of the entry
+func (po *Po) translate(pot *translation, vars ...interface{}) string {
+	if po.missingHandler != nil && !pot.translated(0) {
+		return po.missingHandler(pot.id)
+	}
+	return po.formatTranslation(pot.get(), pot.id, vars...)
+}
+
+// translateN returns the formatted plural form of the entry for n
+func (po *Po) translateN(pot *translation, plural string, n int, vars ...interface{}) string {
+	idx := po.formIndex(pot, n)
+	if po.missingHandler != nil && !pot.translated(idx) {
+		return po.missingHandler(pot.id)
+	}
+	return po.formatTranslation(pot.getN(idx), plural, vars...)
+}
+
+// formatTranslation formats the translated string tr. If safe formatting
+// is enabled and the verbs of tr do not match those of the source string
+// src (e.g. the translator changed them), src is formatted instead, so
+// that corrupt output is never produced.
+func (po *Po) formatTranslation(tr, src string, vars ...interface{}) string {
+	if !po.safeFormat || po.namedPlaceholders || len(vars) == 0 || formatsMatch(src, tr) {
+		return po.format(tr, vars...)
+	}
+
+	if po.formatMismatchHandler != nil {
+		po.formatMismatchHandler(src, tr)
+	}
+	return po.format(src, vars...)
+}
+
+// fprintTranslation writes the formatted translated string tr to w. The
+// output is the same as that of formatTranslation, but it is written
+// directly to w when possible, instead of creating a string first.
+func (po *Po) fprintTranslation(w io.Writer, tr, src string, vars ...interface{}) (int, error) {
+	if po.safeFormat || po.namedPlaceholders {
+		return io.WriteString(w, po.formatTranslation(tr, src, vars...))
+	}
+	return fprint(w, tr, vars...)
+}
+
+// fprint writes the translation of the entry pot to w, or the result
+// for the msgid id if pot is nil or has not been translated. The caller
+// must hold the lock
+func (po *Po) fprint(w io.Writer, pot *translation, id string, vars ...interface{}) (int, error) {
+	if pot == nil || !pot.translated(0) {
+		if po.missingHandler != nil {
+			return io.WriteString(w, po.missingHandler(id))
+		}
+		return po.fprintTranslation(w, id, id, vars...)
+	}
+	return po.fprintTranslation(w, pot.get(), pot.id, vars...)
+}
+
+// rlock takes the read lock, unless the Po object has been sealed.
+// The return value must be passed to runlock
+func (po *Po) rlock() bool {
+	if atomic.LoadInt32(&po.sealed) != 0 {
+		return false
+	}
+	po.mu.RLock()
+	return true
+}
+
+func (po *Po) runlock(locked bool) {
+	if locked {
+		po.mu.RUnlock()
+	}
+}
+
+// Seal marks the Po object as read-only. Lookups on a sealed Po object
+// do not take any locks, which makes them faster when many goroutines
+// translate at the same time. The methods that alter the object fail or
+// have no effect on a sealed Po object: use Clone to create a modifiable
+// copy.
+func (po *Po) Seal() {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	atomic.StoreInt32(&po.sealed, 1)
+}
+
+// Sealed returns true if Seal has been called
+func (po *Po) Sealed() bool {
+	return atomic.LoadInt32(&po.sealed) != 0
+}
+
+// NewPo creates an empty Po object, which can be filled with Set and
+// SetPluralForms. This is useful to build a catalog in memory, for
+// example from a database, without going through the .po format.
+//
+// The options accepted by NewParser, such as WithNamedPlaceholders,
+// may be specified.
+func NewPo(options ...Option) *Po {
+	return NewParser(options...).newPo(0)
+}
+
+// newPo creates an empty Po object, with room for n messages without
+// a context
+func newPo(n int) *Po {
 	return &Po{
-		translations: make(map[string]*translation),
+		translations: make(map[string]*translation, n),
 		contexts:     make(map[string]map[string]*translation),
 	}
 }
 
 // pluralForm calculates the plural form index corresponding to n.
+// If CLDR plural rules are enabled and the catalog's language is known,
+// the index is the position of the CLDR category for n among the
+// categories used by the language. Otherwise the Plural-Forms formula
+// is evaluated.
+//
+// Negative values of n are treated as their absolute value, as GNU gettext
+// does. The formula is evaluated using 64-bit integers, so large values do
+// not overflow.
 // Returns 0 on error
 func (po *Po) pluralForm(n int) int {
+	if n < 0 {
+		n = -n
+		// -n overflows for the smallest negative int
+		if n < 0 {
+			n = int(^uint(0) >> 1)
+		}
+	}
+
+	if po.pluralFunc != nil {
+		idx := po.pluralFunc(n)
+		if idx < 0 || (po.nplurals > 0 && idx >= po.nplurals) {
+			return 0
+		}
+		return idx
+	}
+
+	if po.cldrPlurals {
+		if rule := lookupCLDRRule(po.language); rule != nil {
+			return rule.index(n)
+		}
+	}
+
 	// Failsafe
 	if po.nplurals < 1 {
 		return 0
@@ -69,7 +294,7 @@ func (po *Po) pluralForm(n int) int {
 	}
 
 	env := vm.NewEnv()
-	env.Define("n", n)
+	env.Define("n", int64(n))
 
 	plural, err := vm.Run(po.plural, env)
 	if err != nil {
@@ -83,73 +308,777 @@ func (po *Po) pluralForm(n int) int {
 		return 0
 	}
 
-	if int(plural.Int()) > po.nplurals {
+	// Valid indices are 0 to nplurals-1
+	idx := plural.Int()
+	if idx < 0 || idx >= int64(po.nplurals) {
 		return 0
 	}
 
-	return int(plural.Int())
+	return int(idx)
+}
+
+// parsePluralForms parses the value of a Plural-Forms header, and sets
+// up the plural formula. The caller must hold the lock if necessary
+func (po *Po) parsePluralForms(s string) error {
+	po.pluralErr = nil
+	for _, pf := range strings.Split(s, ";") {
+		vs := strings.SplitN(pf, "=", 2)
+		if len(vs) != 2 {
+			continue
+		}
+
+		switch strings.TrimSpace(vs[0]) {
+		case "nplurals":
+			po.nplurals, _ = strconv.Atoi(strings.TrimSpace(vs[1]))
+
+		case "plural":
+			// compile this now
+			stmts, err := parser.ParseSrc(vs[1])
+			if err != nil {
+				po.pluralErr = errors.Wrap(err, `po: failed to parse plural form spec`)
+				return po.pluralErr
+			}
+			po.plural = stmts
+			po.pluralSrc = strings.TrimSpace(vs[1])
+		}
+	}
+	return nil
+}
+
+// Header returns the value of the given header of the catalog, such as
+// "Language" or "Content-Type". Names are case-insensitive. An empty
+// string is returned if the header is not set.
+func (po *Po) Header(name string) string {
+	defer po.runlock(po.rlock())
+
+	for _, h := range po.headers {
+		if strings.EqualFold(h.name, name) {
+			return h.value
+		}
+	}
+	return ""
+}
+
+// RawHeader returns the header entry (the msgstr of the empty msgid) as
+// it was found in the catalog, before it was split into headers. Only
+// its charset is converted to UTF-8. It is meant for debugging, and for
+// tools that need to preserve the exact formatting of the headers.
+//
+// The raw header is not updated by SetHeader, and it is empty for
+// catalogs that were not parsed.
+func (po *Po) RawHeader() string {
+	defer po.runlock(po.rlock())
+
+	return po.rawHeader
+}
+
+// SetHeader sets the value of the given header, replacing the existing
+// value if any. New headers are added after the existing ones.
+//
+// Setting the Language or Plural-Forms header also changes how plural
+// forms are selected. If the Plural-Forms header cannot be parsed (for
+// example the "nplurals=INTEGER; plural=EXPRESSION;" placeholder of a
+// template), the first form is always selected.
+//
+// SetHeader has no effect once the Po object has been sealed.
+func (po *Po) SetHeader(name, value string) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	if po.Sealed() {
+		return
+	}
+	po.updateHeader(name, value)
+}
+
+// updateHeader stores the header, and updates the settings that depend
+// on it. The caller must hold the lock
+func (po *Po) updateHeader(name, value string) {
+	po.setHeader(name, value)
+
+	switch {
+	case strings.EqualFold(name, "Language"):
+		po.language = value
+		// Without a Plural-Forms header, the formula follows the language
+		if po.pluralForms == "" {
+			po.nplurals, po.plural, po.pluralSrc = 0, nil, ""
+			if err := po.parsePluralForms(lookupPluralForms(value)); err != nil {
+				po.nplurals, po.plural, po.pluralSrc = 0, nil, ""
+			}
+		}
+	case strings.EqualFold(name, "Plural-Forms"):
+		po.pluralForms = value
+		po.nplurals, po.plural, po.pluralSrc = 0, nil, ""
+		if err := po.parsePluralForms(value); err != nil {
+			po.nplurals, po.plural, po.pluralSrc = 0, nil, ""
+		}
+	}
+}
+
+// setHeader stores the header. The caller must hold the lock
+func (po *Po) setHeader(name, value string) {
+	for i, h := range po.headers {
+		if strings.EqualFold(h.name, name) {
+			po.headers[i].value = value
+			return
+		}
+	}
+	po.headers = append(po.headers, header{name: name, value: value})
+}
+
+// SetPluralForms sets the number of plural forms and the formula that
+// selects the plural form for a count n, as they would appear in the
+// Plural-Forms header, e.g. SetPluralForms(2, "(n != 1)").
+func (po *Po) SetPluralForms(nplurals int, formula string) error {
+	if nplurals < 1 {
+		return errors.Errorf(`po: invalid number of plural forms %d`, nplurals)
+	}
+
+	stmts, err := parser.ParseSrc(formula)
+	if err != nil {
+		return errors.Wrap(err, `po: failed to parse plural form spec`)
+	}
+
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	if po.Sealed() {
+		return errors.New(`po: cannot modify a sealed Po`)
+	}
+
+	po.nplurals = nplurals
+	po.plural = stmts
+	po.pluralSrc = formula
+	po.pluralErr = nil
+	po.pluralForms = fmt.Sprintf(`nplurals=%d; plural=%s;`, nplurals, formula)
+	po.setHeader("Plural-Forms", po.pluralForms)
+	return nil
+}
+
+// SetPluralFunc sets a function that selects the plural form for a count
+// n, bypassing both the Plural-Forms formula and the CLDR rules. This is
+// useful for rules that cannot be expressed as a formula, or when the
+// cost of evaluating the formula matters. Negative counts are passed as
+// their absolute value. If the function returns an index that is out of
+// range, the first form is used. Passing nil restores the default.
+//
+// SetPluralFunc has no effect once the Po object has been sealed.
+func (po *Po) SetPluralFunc(f func(n int) int) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	if po.Sealed() {
+		return
+	}
+	po.pluralFunc = f
+}
+
+// Language returns the value of the Language header of the catalog
+func (po *Po) Language() string {
+	defer po.runlock(po.rlock())
+
+	return po.language
+}
+
+// PluralFormError returns the error that occurred while compiling the
+// formula of the Plural-Forms header, or nil if there was none. When the
+// formula cannot be compiled, the first form is always selected, and
+// unless strict parsing is enabled, parsing succeeds regardless. This
+// allows applications to detect such catalogs.
+func (po *Po) PluralFormError() error {
+	defer po.runlock(po.rlock())
+
+	return po.pluralErr
+}
+
+// NPlurals returns the number of plural forms of the catalog, as
+// declared by the Plural-Forms header (or the default for the language
+// if the header is missing). It returns 0 if it is unknown.
+func (po *Po) NPlurals() int {
+	defer po.runlock(po.rlock())
+
+	return po.nplurals
+}
+
+// PluralFormula returns the formula that selects the plural form, e.g.
+// "(n != 1)". It returns an empty string if it is unknown.
+func (po *Po) PluralFormula() string {
+	defer po.runlock(po.rlock())
+
+	return po.pluralSrc
+}
+
+// PluralIndex returns the index of the plural form (i.e. the n in
+// msgstr[n]) that is selected for the count n. This is useful to verify
+// that the Plural-Forms formula of a catalog behaves as expected.
+func (po *Po) PluralIndex(n int) int {
+	return po.pluralForm(n)
 }
 
 // Get retrieves the corresponding translation for the given string.
+// Entries with an empty msgstr are treated as untranslated, as GNU
+// gettext does, so the source string is returned for them.
+// The string may also be given as "msgctxt\x04msgid", the form used
+// by .mo files, to look up an entry with a context.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (po *Po) Get(str string, vars ...interface{}) string {
-	if po.translations == nil {
-		return format(str, vars...)
+	s, _ := po.TryGet(str, vars...)
+	return s
+}
+
+// GetRaw is like Get, but returns the translation exactly as it appears
+// in the catalog, without formatting it. It is meant for messages that
+// contain no verbs, or whose values are substituted by the caller, so
+// that literal percent signs never need to be escaped.
+func (po *Po) GetRaw(str string) string {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.lookup(str)
+	if !ok || !pot.translated(0) {
+		_, id := splitContextKey(str)
+		if po.missingHandler != nil {
+			return po.missingHandler(id)
+		}
+		return id
 	}
+	return pot.get()
+}
 
-	pot, ok := po.translations[str]
+// TryGet is like Get, but the second return value reports whether a
+// translation for the given string was found. If it was not found, or
+// if its msgstr is empty, the formatted source string is returned.
+func (po *Po) TryGet(str string, vars ...interface{}) (string, bool) {
+	defer po.runlock(po.rlock())
+
+	return po.tryGet(str, vars...)
+}
+
+// GetAll returns the translations of all of the given strings, in the
+// same order. It is equivalent to calling Get for each string, but the
+// lock is only taken once, which is faster when translating many strings
+// at a time.
+func (po *Po) GetAll(ids []string) []string {
+	defer po.runlock(po.rlock())
+
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i], _ = po.tryGet(id)
+	}
+	return list
+}
+
+// tryGet implements TryGet. The caller must hold the lock
+func (po *Po) tryGet(str string, vars ...interface{}) (string, bool) {
+	pot, ok := po.lookup(str)
+	if !ok || !pot.translated(0) {
+		_, id := splitContextKey(str)
+		return po.missing(id, id, vars...), false
+	}
+
+	return po.formatTranslation(pot.get(), pot.id, vars...), true
+}
+
+// Fprintf writes the translation of the given string to w, formatted
+// with the given values. The output is the same as that of Get, but
+// unless safe formatting or named placeholders are enabled, it is
+// written directly to w without creating an intermediate string.
+// It returns the number of bytes written and any write error.
+func (po *Po) Fprintf(w io.Writer, str string, vars ...interface{}) (int, error) {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.lookup(str)
+	if !ok {
+		pot = nil
+	}
+	_, id := splitContextKey(str)
+	return po.fprint(w, pot, id, vars...)
+}
+
+// FprintfC is like Fprintf, but writes the translation of the given
+// string in the given context, like GetC.
+func (po *Po) FprintfC(w io.Writer, str, ctx string, vars ...interface{}) (int, error) {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.lookupC(str, ctx)
 	if !ok {
-		return format(str, vars...)
+		pot = nil
+	}
+	return po.fprint(w, pot, str, vars...)
+}
+
+// lookup returns the entry for the given msgid. The msgid may also be
+// a combined "msgctxt\x04msgid" key, as used in .mo files, to look up an
+// entry with a context. The caller must hold the lock
+func (po *Po) lookup(str string) (*translation, bool) {
+	if ctx, id := splitContextKey(str); ctx != "" {
+		pot, ok := po.contexts[ctx][id]
+		return pot, ok
 	}
 
-	return format(pot.get(), vars...)
+	pot, ok := po.translations[str]
+	return pot, ok
 }
 
 // GetN retrieves the (N)th plural form of translation for the given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (po *Po) GetN(str, plural string, n int, vars ...interface{}) string {
-	if po.translations == nil {
-		return format(plural, vars...)
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.lookup(str)
+	if !ok {
+		_, id := splitContextKey(str)
+		return po.missing(id, plural, vars...)
 	}
 
-	pot, ok := po.translations[str]
+	return po.translateN(pot, plural, n, vars...)
+}
+
+// hasTranslation returns true if the catalog has a translation for the
+// given string that GetN would use for n
+func (po *Po) hasTranslation(str string, n int) bool {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.lookup(str)
+	return ok && pot.translated(po.formIndex(pot, n))
+}
+
+// hasTranslationC is like hasTranslation, for the given string in the
+// given context, as GetNC would look it up
+func (po *Po) hasTranslationC(str, ctx string, n int) bool {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.lookupC(str, ctx)
+	return ok && pot.translated(po.formIndex(pot, n))
+}
+
+// formIndex returns the index of the form of the entry to use for n.
+// Entries without msgid_plural and with a single form use it for any n,
+// as GNU gettext does. This matters for entries written with "msgstr[0]",
+// and for languages whose formula does not map 1 to 0. Catalogs that do
+// not record msgid_plural, such as JSON ones, still have several forms
+func (po *Po) formIndex(pot *translation, n int) int {
+	if pot.PluralID == "" && pot.Trs.Len() <= 1 {
+		return 0
+	}
+	return po.pluralForm(n)
+}
+
+// GetCategory is like GetN, but selects the plural form by its CLDR
+// category (one of the Plural* constants) instead of computing it from
+// a count. Categories are mapped to forms using the CLDR rule for the
+// language of the catalog, as given by its Language header.
+//
+// If the language is not known, it does not use the category, or the
+// form has not been translated, the untranslated msgid is returned for
+// PluralOne, and the msgid_plural for the other categories.
+func (po *Po) GetCategory(str, category string, vars ...interface{}) string {
+	defer po.runlock(po.rlock())
+
+	_, id := splitContextKey(str)
+	pot, ok := po.lookup(str)
 	if !ok {
-		return format(plural, vars...)
+		return po.missing(id, id, vars...)
+	}
+
+	src := id
+	if category != PluralOne && pot.PluralID != "" {
+		src = pot.PluralID
 	}
 
-	return format(pot.getN(po.pluralForm(n)), vars...)
+	if rule := lookupCLDRRule(po.language); rule != nil {
+		if idx := rule.categoryIndex(category); idx > -1 && pot.translated(idx) {
+			v, _ := pot.Trs.Get(idx)
+			return po.formatTranslation(v, src, vars...)
+		}
+	}
+	return po.missing(id, src, vars...)
+}
+
+// lookupC returns the entry for the given msgid in the given context,
+// or the entry without context if the context fallback is enabled. The
+// caller must hold the lock
+func (po *Po) lookupC(str, ctx string) (*translation, bool) {
+	if pot, ok := po.contexts[ctx][str]; ok && pot != nil {
+		return pot, true
+	}
+	if po.contextFallback {
+		pot, ok := po.translations[str]
+		return pot, ok
+	}
+	return nil, false
 }
 
 // GetC retrieves the corresponding translation for a given string in the given context.
+// If the Po object was created with WithContextFallback(true), the
+// translation without context is used when the given context has no
+// entry for the string. The precedence is: context entry, context-free
+// entry, then the source string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (po *Po) GetC(str, ctx string, vars ...interface{}) string {
-	if po.contexts != nil {
-		if m, ok := po.contexts[ctx]; ok {
-			if m != nil {
-				if pot, ok := m[str]; ok {
-					return format(pot.get(), vars...)
-				}
-			}
-		}
+	defer po.runlock(po.rlock())
+
+	if pot, ok := po.lookupC(str, ctx); ok {
+		return po.translate(pot, vars...)
 	}
 
 	// Return the string we received by default
-	return format(str, vars...)
+	return po.missing(str, str, vars...)
 }
 
 // GetNC retrieves the (N)th plural form of translation for the given string in the given context.
+// The context fallback rules are the same as GetC.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (po *Po) GetNC(str, plural string, n int, ctx string, vars ...interface{}) string {
-	if po.contexts != nil {
-		if m, ok := po.contexts[ctx]; ok {
-			if m != nil {
-				if pot, ok := m[str]; ok {
-					return format(pot.getN(po.pluralForm(n)), vars...)
-				}
+	defer po.runlock(po.rlock())
+
+	if pot, ok := po.lookupC(str, ctx); ok {
+		return po.translateN(pot, plural, n, vars...)
+	}
+
+	// Return the plural string we received by default
+	return po.missing(str, plural, vars...)
+}
+
+// Message returns the entry for the given msgid, which may be a combined
+// "msgctxt\x04msgid" key
+func (po *Po) Message(str string) (Message, bool) {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.lookup(str)
+	if !ok {
+		return Message{}, false
+	}
+	return pot.message(), true
+}
+
+// MessageC returns the entry for the given msgid in the given context
+func (po *Po) MessageC(str, ctx string) (Message, bool) {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.contexts[ctx][str]
+	if !ok {
+		return Message{}, false
+	}
+	return pot.message(), true
+}
+
+// PluralID returns the msgid_plural of the entry for the given msgid.
+// The second return value is false if there is no such entry, or if the
+// entry is not a plural entry.
+func (po *Po) PluralID(str string) (string, bool) {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.lookup(str)
+	if !ok || pot.PluralID == "" {
+		return "", false
+	}
+	return pot.PluralID, true
+}
+
+// PluralIDC is like PluralID, but looks up the entry in the given context
+func (po *Po) PluralIDC(str, ctx string) (string, bool) {
+	defer po.runlock(po.rlock())
+
+	pot, ok := po.contexts[ctx][str]
+	if !ok || pot.PluralID == "" {
+		return "", false
+	}
+	return pot.PluralID, true
+}
+
+// Duplicates returns the entries that were defined more than once in
+// the catalog, in the order in which the duplicates were found. When an
+// entry is duplicated, the last definition is used. Entries that have a
+// context are reported as the msgctxt and the msgid separated by an EOT
+// byte ("\x04"), as in .mo files.
+//
+// A strict Parser fails on the first duplicate instead.
+func (po *Po) Duplicates() []string {
+	defer po.runlock(po.rlock())
+
+	return append([]string(nil), po.duplicates...)
+}
+
+// ObsoleteMessages returns the obsolete entries (those marked with "#~")
+// in the order they appeared in the catalog. Obsolete entries are never
+// used to look up translations.
+func (po *Po) ObsoleteMessages() []Message {
+	defer po.runlock(po.rlock())
+
+	list := make([]Message, len(po.obsolete))
+	for i, t := range po.obsolete {
+		list[i] = t.message()
+	}
+	return list
+}
+
+// Each calls fn for each entry of the catalog, except for the header and
+// the obsolete entries, until fn returns false. The order of iteration is
+// unspecified and may change between calls. Use EachSorted when the
+// output must be reproducible.
+func (po *Po) Each(fn func(Message) bool) {
+	po.each(fn, false)
+}
+
+// EachSorted is like Each, but visits the entries without a context
+// first, sorted by msgid, followed by the entries with a context, sorted
+// by msgctxt and then by msgid. This is the same order that WritePO uses.
+func (po *Po) EachSorted(fn func(Message) bool) {
+	po.each(fn, true)
+}
+
+func (po *Po) each(fn func(Message) bool, sorted bool) {
+	// The messages are collected first, so that fn may modify the catalog
+	list := po.messages(sorted)
+	for _, m := range list {
+		if !fn(m) {
+			return
+		}
+	}
+}
+
+func (po *Po) messages(sorted bool) []Message {
+	defer po.runlock(po.rlock())
+
+	n := len(po.translations)
+	for _, m := range po.contexts {
+		n += len(m)
+	}
+	list := make([]Message, 0, n)
+
+	if !sorted {
+		for _, t := range po.translations {
+			list = append(list, t.message())
+		}
+		for _, m := range po.contexts {
+			for _, t := range m {
+				list = append(list, t.message())
 			}
 		}
+		return list
 	}
 
-	// Return the plural string we received by default
-	return format(plural, vars...)
+	for _, id := range sortedKeys(po.translations) {
+		list = append(list, po.translations[id].message())
+	}
+
+	ctxs := make([]string, 0, len(po.contexts))
+	for ctx := range po.contexts {
+		ctxs = append(ctxs, ctx)
+	}
+	sort.Strings(ctxs)
+	for _, ctx := range ctxs {
+		m := po.contexts[ctx]
+		for _, id := range sortedKeys(m) {
+			list = append(list, m[id].message())
+		}
+	}
+	return list
+}
+
+// Validate cross-checks each plural entry in the catalog against the
+// number of plural forms declared by the Plural-Forms header, and
+// returns all of the problems that were found (nil if there were none).
+//
+// An entry is reported if it contains more forms than nplurals, or if
+// any of the required forms is missing or empty. Entries that have not
+// been translated at all are not reported.
+func (po *Po) Validate() []error {
+	defer po.runlock(po.rlock())
+
+	if po.nplurals < 1 {
+		return nil
+	}
+
+	var errs []error
+	check := func(ctx string, t *translation) {
+		if t.PluralID == "" {
+			return
+		}
+
+		var where string
+		if ctx == "" {
+			where = "msgid " + strconv.Quote(t.id)
+		} else {
+			where = "msgid " + strconv.Quote(t.id) + " (msgctxt " + strconv.Quote(ctx) + ")"
+		}
+
+		if t.Trs.Len() > po.nplurals {
+			errs = append(errs, errors.Errorf(`po: %s has %d forms, but nplurals is %d`, where, t.Trs.Len(), po.nplurals))
+		}
+
+		var translated bool
+		for i := 0; i < t.Trs.Len(); i++ {
+			if v, ok := t.Trs.Get(i); ok && v != "" {
+				translated = true
+				break
+			}
+		}
+		if !translated {
+			return
+		}
+
+		for i := 0; i < po.nplurals; i++ {
+			v, ok := t.Trs.Get(i)
+			switch {
+			case !ok:
+				errs = append(errs, errors.Errorf(`po: %s is missing msgstr[%d]`, where, i))
+			case v == "":
+				errs = append(errs, errors.Errorf(`po: %s has an empty msgstr[%d]`, where, i))
+			}
+		}
+	}
+
+	for _, id := range sortedKeys(po.translations) {
+		check("", po.translations[id])
+	}
+
+	ctxs := make([]string, 0, len(po.contexts))
+	for ctx := range po.contexts {
+		ctxs = append(ctxs, ctx)
+	}
+	sort.Strings(ctxs)
+
+	for _, ctx := range ctxs {
+		m := po.contexts[ctx]
+		for _, id := range sortedKeys(m) {
+			check(ctx, m[id])
+		}
+	}
+
+	return errs
+}
+
+func sortedKeys(m map[string]*translation) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// clone returns a deep copy of the translation
+func (t *translation) clone() *translation {
+	c := *t
+	c.Trs = append(textlist(nil), t.Trs...)
+	c.Comments = append([]Comment(nil), t.Comments...)
+	return &c
+}
+
+// Clone returns a deep copy of the Po object. The copy can be modified
+// with Set and Delete without affecting the original, which makes it
+// possible to prepare a modified catalog and swap it in while other
+// goroutines are still using the original.
+func (po *Po) Clone() *Po {
+	defer po.runlock(po.rlock())
+
+	c := newPo(len(po.translations))
+	c.language = po.language
+	c.pluralForms = po.pluralForms
+	c.headers = append([]header(nil), po.headers...)
+	c.rawHeader = po.rawHeader
+	c.nplurals = po.nplurals
+	// The compiled formula is never modified, so it can be shared
+	c.plural = po.plural
+	c.pluralSrc = po.pluralSrc
+	c.pluralErr = po.pluralErr
+	c.pluralFunc = po.pluralFunc
+	c.namedPlaceholders = po.namedPlaceholders
+	c.safeFormat = po.safeFormat
+	c.formatMismatchHandler = po.formatMismatchHandler
+	c.missingHandler = po.missingHandler
+	c.cldrPlurals = po.cldrPlurals
+	c.contextFallback = po.contextFallback
+	c.filename = po.filename
+	c.duplicates = append([]string(nil), po.duplicates...)
+
+	for id, t := range po.translations {
+		c.translations[id] = t.clone()
+	}
+	for ctx, m := range po.contexts {
+		cm := make(map[string]*translation, len(m))
+		for id, t := range m {
+			cm[id] = t.clone()
+		}
+		c.contexts[ctx] = cm
+	}
+	if po.obsolete != nil {
+		c.obsolete = make([]*translation, len(po.obsolete))
+		for i, t := range po.obsolete {
+			c.obsolete[i] = t.clone()
+		}
+	}
+	return c
+}
+
+// Set adds a translation for the given msgid (and msgctxt, if not empty),
+// replacing any existing entry. For plural entries msgidPlural must be
+// non-empty, and forms should contain each plural form in order.
+//
+// Set and Delete are safe to call while other goroutines are looking up
+// translations. They have no effect once the Po object has been sealed.
+func (po *Po) Set(msgctxt, msgid, msgidPlural string, forms []string) {
+	t := newTranslation()
+	t.id = msgid
+	t.ctx = msgctxt
+	t.PluralID = msgidPlural
+	for i, form := range forms {
+		t.Trs.Set(i, form)
+	}
+
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	if po.Sealed() {
+		return
+	}
+	po.add(t)
+}
+
+// add stores the translation, replacing any existing entry. The caller
+// must hold the lock
+func (po *Po) add(t *translation) {
+	if t.ctx == "" {
+		if po.translations == nil {
+			po.translations = make(map[string]*translation)
+		}
+		po.translations[t.id] = t
+		return
+	}
+
+	if po.contexts == nil {
+		po.contexts = make(map[string]map[string]*translation)
+	}
+	m, ok := po.contexts[t.ctx]
+	if !ok {
+		m = make(map[string]*translation)
+		po.contexts[t.ctx] = m
+	}
+	m[t.id] = t
+}
+
+// Delete removes the translation for the given msgid (and msgctxt, if
+// not empty). It is a no-op if there is no such entry.
+func (po *Po) Delete(msgctxt, msgid string) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	if po.Sealed() {
+		return
+	}
+
+	if msgctxt == "" {
+		delete(po.translations, msgid)
+		return
+	}
+
+	m, ok := po.contexts[msgctxt]
+	if !ok {
+		return
+	}
+	delete(m, msgid)
+	if len(m) == 0 {
+		delete(po.contexts, msgctxt)
+	}
 }
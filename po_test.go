@@ -1,10 +1,19 @@
 package gettext
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -206,6 +215,31 @@ msgstr "Translated example"
 	}
 }
 
+func TestPoRawHeader(t *testing.T) {
+	str := "msgid \"\"\n" +
+		"msgstr \"\"\n" +
+		"\"Language:  fr\\n\"\n" +
+		"\"Content-Type: text/plain; charset=ISO-8859-1\\n\"\n" +
+		"\"Last-Translator: Fran\xe7ois\\n\"\n" +
+		"\n" +
+		"msgid \"Example\"\n" +
+		"msgstr \"Exemple\"\n"
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	raw := "Language:  fr\nContent-Type: text/plain; charset=ISO-8859-1\nLast-Translator: François\n"
+	assert.Equal(t, raw, po.RawHeader(), `raw header should keep its formatting`)
+	assert.Equal(t, "text/plain; charset=UTF-8", po.Header("Content-Type"), `parsed header should be converted`)
+
+	po.SetHeader("Language", "fr_FR")
+	assert.Equal(t, raw, po.RawHeader(), `SetHeader should not change the raw header`)
+	assert.Equal(t, raw, po.Clone().RawHeader(), `Clone should copy the raw header`)
+	assert.Equal(t, "", NewPo().RawHeader(), `raw header should be empty`)
+}
+
 func TestPluralFormsSingle(t *testing.T) {
 	// Single form
 	str := `
@@ -402,3 +436,2033 @@ msgstr[0] "Badly formatted string'
 	}
 	_ = po
 }
+
+func TestPoOutOfOrderPlurals(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3; plural=n==1 ? 0 : n==2 ? 1 : 2;\n"
+
+msgid "Sparse"
+msgid_plural "Sparse plural"
+msgstr[2] "Sparse form 2"
+msgstr[0] "Sparse form 0"
+
+msgid "Reversed"
+msgid_plural "Reversed plural"
+msgstr[2] "Reversed form 2"
+msgstr[1] "Reversed form 1"
+msgstr[0] "Reversed "
+"form 0"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "Sparse form 0", po.GetN("Sparse", "Sparse plural", 1), "form 0")
+	assert.Equal(t, "Sparse plural", po.GetN("Sparse", "Sparse plural", 2), "missing form 1 falls back to msgid_plural")
+	assert.Equal(t, "Sparse form 2", po.GetN("Sparse", "Sparse plural", 5), "form 2")
+
+	assert.Equal(t, "Reversed form 0", po.GetN("Reversed", "Reversed plural", 1), "form 0 (with continuation)")
+	assert.Equal(t, "Reversed form 1", po.GetN("Reversed", "Reversed plural", 2), "form 1")
+	assert.Equal(t, "Reversed form 2", po.GetN("Reversed", "Reversed plural", 5), "form 2")
+}
+
+func TestPoTooManyPluralsStrict(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=n != 1;\n"
+
+msgid "One"
+msgid_plural "Many"
+msgstr[0] "One form"
+msgstr[2] "Form 2"
+`
+
+	_, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	assert.Error(t, err, `msgstr[2] should be rejected when nplurals=2 (strict == true)`)
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed (strict == false)`) {
+		return
+	}
+	assert.Equal(t, "Many", po.GetN("One", "Many", 2), "missing form 1 falls back to msgid_plural")
+}
+
+func TestPoValidate(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3; plural=n==1 ? 0 : n==2 ? 1 : 2;\n"
+
+msgid "Complete"
+msgid_plural "Complete plural"
+msgstr[0] "Complete form 0"
+msgstr[1] "Complete form 1"
+msgstr[2] "Complete form 2"
+
+msgid "Missing"
+msgid_plural "Missing plural"
+msgstr[0] "Missing form 0"
+msgstr[1] "Missing form 1"
+
+msgctxt "Ctx"
+msgid "Empty"
+msgid_plural "Empty plural"
+msgstr[0] "Empty form 0"
+msgstr[1] ""
+msgstr[2] "Empty form 2"
+
+msgid "Untranslated"
+msgid_plural "Untranslated plural"
+msgstr[0] ""
+msgstr[1] ""
+msgstr[2] ""
+
+msgid "Singular"
+msgstr "Singular form"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	errs := po.Validate()
+	if !assert.Len(t, errs, 2, `Validate should report 2 problems`) {
+		return
+	}
+	assert.Contains(t, errs[0].Error(), `"Missing" is missing msgstr[2]`)
+	assert.Contains(t, errs[1].Error(), `"Empty" (msgctxt "Ctx") has an empty msgstr[1]`)
+
+	_, err = NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.Error(t, err, `ParseString should fail (strict == true)`) {
+		return
+	}
+	merr, ok := errors.Cause(err).(MultiError)
+	if assert.True(t, ok, `error should be a MultiError`) {
+		assert.Len(t, merr.Errors(), 2, `all of the problems should be reported`)
+	}
+}
+
+func TestStrictParsingErrors(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Input string
+		Line  string
+	}{
+		{
+			Name: "malformed msgid",
+			Input: `msgid "Good"
+msgstr "Good translation"
+
+msgid Bad"
+msgstr "Bad translation"
+`,
+			Line: "line 4",
+		},
+		{
+			Name: "unterminated multi-line string",
+			Input: `msgid "Good"
+msgstr "Good "
+"translation
+`,
+			Line: "line 3",
+		},
+		{
+			Name: "bad index",
+			Input: `msgid "Good"
+msgid_plural "Goods"
+msgstr[0] "Good translation"
+msgstr[x] "Goods translation"
+`,
+			Line: "line 4",
+		},
+		{
+			Name: "unexpected content",
+			Input: `msgid "Good"
+msgstr "Good translation"
+garbage
+`,
+			Line: "line 3",
+		},
+	}
+
+	for _, c := range cases {
+		_, err := NewParser(WithStrictParsing(true)).ParseString(c.Input)
+		if !assert.Error(t, err, c.Name+`: ParseString should fail (strict == true)`) {
+			continue
+		}
+		assert.Contains(t, err.Error(), c.Line, c.Name+`: error should report the offending line`)
+
+		_, err = NewParser().ParseString(c.Input)
+		assert.NoError(t, err, c.Name+`: ParseString should succeed (strict == false)`)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	str := `msgid "Good"
+msgstr "Good translation"
+
+msgid "Bad
+msgstr "Bad translation"
+`
+
+	_, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.Error(t, err, `ParseString should fail (strict == true)`) {
+		return
+	}
+
+	perr, ok := errors.Cause(err).(*ParseError)
+	if !assert.True(t, ok, `error should be a *ParseError`) {
+		return
+	}
+	assert.Equal(t, 4, perr.Line, `ParseError.Line should point to the offending line`)
+	assert.Equal(t, "failed to parse msgid", perr.Message)
+}
+
+func TestParseIndexOutOfRange(t *testing.T) {
+	str := `msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[100000000000] "Trop"
+msgstr[1] "%d fichiers"
+`
+
+	_, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.Error(t, err, `ParseString should fail (strict == true)`) {
+		return
+	}
+	perr, ok := errors.Cause(err).(*ParseError)
+	if !assert.True(t, ok, `error should be a *ParseError`) {
+		return
+	}
+	assert.Equal(t, 4, perr.Line, `ParseError.Line should point to the offending line`)
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed (strict == false)`) {
+		return
+	}
+	assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2), `the offending line should be skipped`)
+}
+
+func TestParseDuplicates(t *testing.T) {
+	str := `msgid "Hello"
+msgstr "Bonjour"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgid "Hello"
+msgstr "Salut"
+
+msgid "Open"
+msgstr "Ouvrir"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrez"
+
+#~ msgid "Hello"
+#~ msgstr "Allô"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed (strict == false)`) {
+		return
+	}
+	assert.Equal(t, []string{"Hello", "menu\x04Open"}, po.Duplicates(), `duplicates should be recorded`)
+	assert.Equal(t, "Salut", po.Get("Hello"), `last definition should win`)
+	assert.Equal(t, "Ouvrez", po.GetC("Open", "menu"), `last definition should win`)
+	assert.Equal(t, po.Duplicates(), po.Clone().Duplicates(), `Clone should copy duplicates`)
+
+	_, err = NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.Error(t, err, `ParseString should fail (strict == true)`) {
+		return
+	}
+	perr, ok := errors.Cause(err).(*ParseError)
+	if !assert.True(t, ok, `error should be a *ParseError`) {
+		return
+	}
+	assert.Equal(t, 8, perr.Line, `ParseError.Line should point to the second occurrence`)
+
+	// The duplicate may be the last entry of the catalog
+	_, err = NewParser(WithStrictParsing(true)).ParseString("msgid \"A\"\nmsgstr \"a\"\n\nmsgid \"A\"\nmsgstr \"b\"\n")
+	if !assert.Error(t, err, `ParseString should fail (strict == true)`) {
+		return
+	}
+	perr, ok = errors.Cause(err).(*ParseError)
+	if !assert.True(t, ok, `error should be a *ParseError`) {
+		return
+	}
+	assert.Equal(t, 4, perr.Line, `ParseError.Line should point to the second occurrence`)
+
+	po, err = NewParser().ParseString("msgid \"A\"\nmsgstr \"a\"\n")
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Empty(t, po.Duplicates(), `no duplicates should be recorded`)
+}
+
+func TestPoEscapeSequences(t *testing.T) {
+	// Catalog as exported by Poedit, containing escapes and literal
+	// tabs that strconv.Unquote rejects
+	str := "\n" +
+		"msgid \"\"\n" +
+		"msgstr \"\"\n" +
+		"\"Content-Type: text/plain; charset=UTF-8\\n\"\n" +
+		"\"X-Generator: Poedit 2.0.6\\n\"\n" +
+		"\n" +
+		"msgid \"Escape\"\n" +
+		"msgstr \"\\e[1mBold\\e[0m\"\n" +
+		"\n" +
+		"msgid \"Tab\"\n" +
+		"msgstr \"Literal\ttab\"\n" +
+		"\n" +
+		"msgid \"Controls\"\n" +
+		"msgstr \"\\a\\b\\f\\v\\r\\n\\t\\\"\\\\\"\n" +
+		"\n" +
+		"msgid \"Numeric\"\n" +
+		"msgstr \"\\101\\x42\\103\"\n"
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "\x1b[1mBold\x1b[0m", po.Get("Escape"), `\e escape`)
+	assert.Equal(t, "Literal\ttab", po.Get("Tab"), `literal tab`)
+	assert.Equal(t, "\a\b\f\v\r\n\t\"\\", po.Get("Controls"), `control escapes`)
+	assert.Equal(t, "ABC", po.Get("Numeric"), `octal and hex escapes`)
+
+	for _, bad := range []string{`"\q"`, `"\"`, `"a"b"`, `"\x"`, `"\777"`, `noquotes`} {
+		_, err := unquote(bad)
+		assert.Error(t, err, `unquote should fail for `+bad)
+	}
+}
+
+func TestPoMultiLineContext(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Language: fr\n"
+
+msgctxt ""
+"This is a very long context that has been wrapped by the tools, "
+"because it did not fit on a single line"
+msgid "Open"
+msgstr "Ouvrir"
+
+#~ msgctxt ""
+#~ "Obsolete "
+#~ "context"
+#~ msgid "Close"
+#~ msgstr "Fermer"
+`
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	ctx := "This is a very long context that has been wrapped by the tools, because it did not fit on a single line"
+	assert.Equal(t, "Ouvrir", po.GetC("Open", ctx))
+	assert.Equal(t, "fr", po.language, `context should not be treated as a header`)
+
+	obsolete := po.ObsoleteMessages()
+	if assert.Len(t, obsolete, 1) {
+		assert.Equal(t, "Obsolete context", obsolete[0].Context)
+	}
+}
+
+func TestPoMultiLineID(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid ""
+"This is a long message "
+"that has been wrapped"
+msgstr ""
+"Ceci est un long message "
+"qui a été découpé"
+
+msgid ""
+"One long "
+"file"
+msgid_plural ""
+"%d long "
+"files"
+msgstr[0] "Un long fichier"
+msgstr[1] ""
+"%d longs "
+"fichiers"
+`
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "fr", po.language, `msgid should not be treated as a header`)
+	assert.Equal(t, "Ceci est un long message qui a été découpé", po.Get("This is a long message that has been wrapped"))
+	assert.Equal(t, "Un long fichier", po.GetN("One long file", "%d long files", 1))
+	assert.Equal(t, "3 longs fichiers", po.GetN("One long file", "%d long files", 3, 3))
+
+	v, ok := po.PluralID("One long file")
+	assert.True(t, ok, `PluralID should succeed`)
+	assert.Equal(t, "%d long files", v)
+}
+
+func TestPoObsoleteMessages(t *testing.T) {
+	str := `
+msgid "Current"
+msgstr "Current translation"
+
+#~ msgid "Old"
+#~ msgstr "Old "
+#~ "translation"
+
+#, fuzzy
+#~| msgid "Older plural"
+#~ msgid "Old plural"
+#~ msgid_plural "Old plurals"
+#~ msgstr[0] "Old plural form 0"
+#~ msgstr[1] "Old plural form 1"
+`
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "Current translation", po.Get("Current"))
+	assert.Equal(t, "Old", po.Get("Old"), `obsolete entries should not be used for lookups`)
+
+	expected := []Message{
+		{ID: "Old", Strings: []string{"Old translation"}},
+		{ID: "Old plural", PluralID: "Old plurals", Strings: []string{"Old plural form 0", "Old plural form 1"}, PreviousID: "Older plural",
+			Comments: []Comment{{Type: CommentFlag, Text: "fuzzy"}, {Type: CommentObsolete, Text: `msgid "Older plural"`}}},
+	}
+	assert.Equal(t, expected, po.ObsoleteMessages())
+}
+
+func TestPoEach(t *testing.T) {
+	po := NewPo()
+	po.Set("menu", "Open", "", []string{"Abrir (menu)"})
+	po.Set("", "Zebra", "", []string{"Cebra"})
+	po.Set("", "Apple", "", []string{"Manzana"})
+	po.Set("button", "Open", "", []string{"Abrir (button)"})
+	po.Set("button", "Close", "", []string{"Cerrar"})
+
+	var keys []string
+	po.EachSorted(func(m Message) bool {
+		keys = append(keys, m.Context+"|"+m.ID)
+		return true
+	})
+	assert.Equal(t, []string{"|Apple", "|Zebra", "button|Close", "button|Open", "menu|Open"}, keys)
+
+	var unordered []string
+	po.Each(func(m Message) bool {
+		unordered = append(unordered, m.Context+"|"+m.ID)
+		return true
+	})
+	sort.Strings(unordered)
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	assert.Equal(t, sorted, unordered, `Each should visit every entry`)
+
+	var count int
+	po.EachSorted(func(m Message) bool {
+		count++
+		// The catalog may be modified while iterating
+		po.Delete(m.Context, m.ID)
+		return count < 2
+	})
+	assert.Equal(t, 2, count, `iteration should stop when fn returns false`)
+	assert.Equal(t, "Apple", po.Get("Apple"), `entry should have been deleted`)
+	assert.Equal(t, "Cerrar", po.GetC("Close", "button"), `entry should not have been visited`)
+}
+
+func TestParseCharset(t *testing.T) {
+	header := func(charset string) string {
+		return "msgid \"\"\nmsgstr \"\"\n\"Content-Type: text/plain; charset=" + charset + "\\n\"\n\n"
+	}
+
+	testcases := []struct {
+		charset  string
+		msgstr   string
+		expected string
+	}{
+		{"UTF-8", "Caf\xc3\xa9", "Café"},
+		{"CHARSET", "Caf\xc3\xa9", "Café"},
+		{"ISO-8859-1", "Caf\xe9 \xa4", "Café ¤"},
+		{"latin1", "Caf\xe9", "Café"},
+		{"ISO-8859-15", "Caf\xe9 \xa4", "Café €"},
+		{"windows-1252", "\x93Caf\xe9\x94", "“Café”"},
+		{"ISO-8859-2", "\xa3\xf3d\xbc", "Łódź"},
+		{"KOI8-R", "\xf0\xd2\xc9\xd7\xc5\xd4", "Привет"},
+		{"windows-1251", "\xcf\xf0\xe8\xe2\xe5\xf2", "Привет"},
+	}
+
+	for _, tc := range testcases {
+		str := header(tc.charset) + "#. Caf\xe9\nmsgid \"Coffee\"\nmsgstr \"" + tc.msgstr + "\"\n"
+		po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+		if !assert.NoError(t, err, `ParseString should succeed for `+tc.charset) {
+			continue
+		}
+		assert.Equal(t, tc.expected, po.Get("Coffee"), `translation should be converted from `+tc.charset)
+	}
+
+	po, err := NewParser().ParseString(header("ISO-8859-1") + "msgid \"Caf\xe9\"\nmsgstr \"Kaffee\"\n")
+	if assert.NoError(t, err, `ParseString should succeed`) {
+		assert.Equal(t, "Kaffee", po.Get("Café"), `msgid should be converted`)
+		assert.Equal(t, "text/plain; charset=UTF-8", po.Header("Content-Type"))
+	}
+
+	// Unknown charsets are only a problem for strings that are not UTF-8
+	po, err = NewParser(WithStrictParsing(true)).ParseString(header("X-UNKNOWN") + "msgid \"Hello\"\nmsgstr \"Caf\xc3\xa9\"\n")
+	if assert.NoError(t, err, `valid UTF-8 should be accepted with an unknown charset`) {
+		assert.Equal(t, "Café", po.Get("Hello"))
+	}
+
+	str := header("X-UNKNOWN") + "msgid \"Hello\"\nmsgstr \"Caf\xe9\"\n"
+	_, err = NewParser(WithStrictParsing(true)).ParseString(str)
+	assert.Error(t, err, `unsupported charset should be reported in strict mode`)
+
+	po, err = NewParser().ParseString(str)
+	if assert.NoError(t, err, `ParseString should succeed in non-strict mode`) {
+		assert.Equal(t, "Caf\uFFFD", po.Get("Hello"), `invalid bytes should be replaced`)
+	}
+}
+
+func TestParseTrimSpace(t *testing.T) {
+	str := `
+msgid "Hello"
+msgstr "  Bonjour	 "
+
+msgid "Two words"
+msgstr " Deux  mots "
+
+msgid "Line\n"
+msgstr "Ligne \n"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier "
+msgstr[1] " %d fichiers"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "  Bonjour	 ", po.Get("Hello"), `spaces should be kept by default`)
+
+	po, err = NewParser(WithTrimSpace(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "Bonjour", po.Get("Hello"))
+	assert.Equal(t, "Deux  mots", po.Get("Two words"), `inner spaces should be kept`)
+	assert.Equal(t, "Ligne \n", po.Get("Line\n"), `newlines should be kept`)
+	assert.Equal(t, "Un fichier", po.GetN("One file", "%d files", 1))
+	assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2))
+}
+
+func TestPoMissingHandler(t *testing.T) {
+	str := `
+msgid "greeting"
+msgstr "Hello, %s"
+
+msgid "untranslated"
+msgstr ""
+
+msgctxt "menu"
+msgid "file.open"
+msgstr "Open"
+
+msgid "file.count"
+msgid_plural "file.count.plural"
+msgstr[0] "%d file"
+msgstr[1] ""
+`
+
+	var missed []string
+	handler := func(msgid string) string {
+		missed = append(missed, msgid)
+		return "«missing: " + msgid + "»"
+	}
+
+	po, err := NewParser(WithMissingHandler(handler)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "Hello, John", po.Get("greeting", "John"))
+	assert.Equal(t, "«missing: farewell»", po.Get("farewell", "John"), `missing entry should use the handler`)
+	assert.Equal(t, "«missing: untranslated»", po.Get("untranslated"), `untranslated entry should use the handler`)
+	v, ok := po.TryGet("farewell")
+	assert.False(t, ok, `TryGet should report the miss`)
+	assert.Equal(t, "«missing: farewell»", v)
+
+	assert.Equal(t, "Open", po.GetC("file.open", "menu"))
+	assert.Equal(t, "«missing: file.close»", po.GetC("file.close", "menu"))
+	assert.Equal(t, "«missing: file.open»", po.GetNC("file.open", "file.open.plural", 2, "toolbar"))
+
+	assert.Equal(t, "1 file", po.GetN("file.count", "file.count.plural", 1, 1))
+	assert.Equal(t, "«missing: file.count»", po.GetN("file.count", "file.count.plural", 2, 2), `untranslated form should use the handler`)
+	assert.Equal(t, "«missing: dir.count»", po.GetN("dir.count", "dir.count.plural", 2, 2))
+
+	assert.Equal(t, []string{"farewell", "untranslated", "farewell", "file.close", "file.open", "file.count", "dir.count"}, missed)
+
+	empty, err := NewParser(WithMissingHandler(func(string) string { return "" })).ParseString(str)
+	if assert.NoError(t, err, `ParseString should succeed`) {
+		assert.Equal(t, "", empty.Get("farewell"), `handler may return an empty string`)
+		assert.Equal(t, "", empty.Clone().Get("farewell"), `handler should be copied by Clone`)
+	}
+}
+
+func TestPoPreviousMarkers(t *testing.T) {
+	str := `
+#, fuzzy
+#| msgctxt "Old context"
+#| msgid "Old "
+#| "text"
+#| msgid_plural "Old texts"
+msgctxt "Ctx"
+msgid "New text"
+msgid_plural "New texts"
+msgstr[0] "Translated text"
+msgstr[1] "Translated texts"
+
+#, fuzzy
+#| msgid "Hello"
+msgid "Hello!"
+msgstr "Bonjour"
+
+msgid "Unchanged"
+msgstr "Inchangé"
+`
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "Bonjour", po.Get("Hello!"), `previous markers should not interfere with lookups`)
+	assert.Equal(t, "Hello", po.Get("Hello"), `previous msgid should not be registered`)
+
+	m, ok := po.MessageC("New text", "Ctx")
+	if assert.True(t, ok, `MessageC should succeed`) {
+		assert.Equal(t, "Old context", m.PreviousContext)
+		assert.Equal(t, "Old text", m.PreviousID)
+		assert.Equal(t, "Old texts", m.PreviousPluralID)
+	}
+
+	m, ok = po.Message("Hello!")
+	if assert.True(t, ok, `Message should succeed`) {
+		assert.Equal(t, "", m.PreviousContext)
+		assert.Equal(t, "Hello", m.PreviousID)
+	}
+
+	m, ok = po.Message("Unchanged")
+	if assert.True(t, ok, `Message should succeed`) {
+		assert.Equal(t, "", m.PreviousID, `markers should not leak into following entries`)
+	}
+}
+
+func TestPoComments(t *testing.T) {
+	str := `
+# Header comment
+msgid ""
+msgstr ""
+
+# Translator comment
+#
+#. Extracted comment
+#: src/main.c:10 src/main.c:20
+#, fuzzy, c-format
+#| msgid "Old %d"
+msgctxt "Ctx"
+msgid "New %d"
+msgstr "Nouveau %d"
+
+msgid "No comments"
+msgstr "Pas de commentaires"
+
+# Obsolete comment
+#~| msgid "Older"
+#~ msgid "Old"
+#~ msgstr "Vieux"
+`
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	m, ok := po.MessageC("New %d", "Ctx")
+	if assert.True(t, ok, `MessageC should succeed`) {
+		assert.Equal(t, []Comment{
+			{Type: CommentTranslator, Text: "Translator comment"},
+			{Type: CommentTranslator, Text: ""},
+			{Type: CommentExtracted, Text: "Extracted comment"},
+			{Type: CommentReference, Text: "src/main.c:10 src/main.c:20"},
+			{Type: CommentFlag, Text: "fuzzy, c-format"},
+			{Type: CommentPrevious, Text: `msgid "Old %d"`},
+		}, m.Comments)
+	}
+
+	m, ok = po.Message("No comments")
+	if assert.True(t, ok, `Message should succeed`) {
+		assert.Empty(t, m.Comments, `comments should not leak into following entries`)
+	}
+
+	obsolete := po.ObsoleteMessages()
+	if assert.Len(t, obsolete, 1) {
+		assert.Equal(t, []Comment{
+			{Type: CommentTranslator, Text: "Obsolete comment"},
+			{Type: CommentObsolete, Text: `msgid "Older"`},
+		}, obsolete[0].Comments)
+	}
+}
+
+func TestPoPluralID(t *testing.T) {
+	str := `
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "Ctx"
+msgid "One folder"
+msgid_plural "%d folders"
+msgstr[0] "Un dossier"
+msgstr[1] "%d dossiers"
+
+msgid "Singular"
+msgstr "Singulier"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	v, ok := po.PluralID("One file")
+	assert.True(t, ok, `PluralID should succeed`)
+	assert.Equal(t, "%d files", v)
+
+	_, ok = po.PluralID("Singular")
+	assert.False(t, ok, `PluralID should fail for singular entries`)
+
+	_, ok = po.PluralID("Unknown")
+	assert.False(t, ok, `PluralID should fail for unknown entries`)
+
+	_, ok = po.PluralID("One folder")
+	assert.False(t, ok, `PluralID should not look into contexts`)
+
+	v, ok = po.PluralIDC("One folder", "Ctx")
+	assert.True(t, ok, `PluralIDC should succeed`)
+	assert.Equal(t, "%d folders", v)
+
+	_, ok = po.PluralIDC("One folder", "Other")
+	assert.False(t, ok, `PluralIDC should fail for unknown contexts`)
+}
+
+func TestPoSetDelete(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=n != 1;\n"
+
+msgid "Existing"
+msgstr "Existing translation"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	po.Set("", "Existing", "", []string{"Updated translation"})
+	assert.Equal(t, "Updated translation", po.Get("Existing"))
+
+	po.Set("", "File", "Files", []string{"%d file", "%d files"})
+	assert.Equal(t, "1 file", po.GetN("File", "Files", 1, 1))
+	assert.Equal(t, "3 files", po.GetN("File", "Files", 3, 3))
+
+	po.Set("Menu", "Open", "", []string{"Open menu"})
+	assert.Equal(t, "Open menu", po.GetC("Open", "Menu"))
+
+	po.Delete("", "Existing")
+	assert.Equal(t, "Existing", po.Get("Existing"))
+
+	po.Delete("Menu", "Open")
+	assert.Equal(t, "Open", po.GetC("Open", "Menu"))
+
+	// Concurrent readers and writers
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				po.Set("", "Racy", "", []string{"Racy translation"})
+				po.Delete("", "Racy")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				po.Get("Racy")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPoClone(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	c := po.Clone()
+	assert.Equal(t, "Bonjour", c.Get("Hello"))
+	assert.Equal(t, "Un fichier", c.GetN("One file", "%d files", 1))
+	assert.Equal(t, "2 fichiers", c.GetN("One file", "%d files", 2, 2), `plural formula should be copied`)
+	assert.Equal(t, "Ouvrir", c.GetC("Open", "menu"))
+
+	c.Set("", "Hello", "", []string{"Salut"})
+	c.Set("menu", "Open", "", []string{"Ouvrir…"})
+	c.Set("menu", "Close", "", []string{"Fermer"})
+	c.Delete("", "One file")
+
+	assert.Equal(t, "Salut", c.Get("Hello"))
+	assert.Equal(t, "Ouvrir…", c.GetC("Open", "menu"))
+	assert.Equal(t, "Fermer", c.GetC("Close", "menu"))
+	assert.Equal(t, "2 files", c.GetN("One file", "%d files", 2, 2))
+
+	assert.Equal(t, "Bonjour", po.Get("Hello"), `original should not be modified`)
+	assert.Equal(t, "Ouvrir", po.GetC("Open", "menu"), `original should not be modified`)
+	assert.Equal(t, "Close", po.GetC("Close", "menu"), `original should not be modified`)
+	assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2), `original should not be modified`)
+}
+
+func TestNewPo(t *testing.T) {
+	po := NewPo()
+	assert.Equal(t, "Hello", po.Get("Hello"), `empty Po should not translate`)
+
+	assert.Error(t, po.SetPluralForms(0, "0"), `nplurals must be positive`)
+	assert.Error(t, po.SetPluralForms(2, "(n != "), `formula must be valid`)
+	if !assert.NoError(t, po.SetPluralForms(3, "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)"), `SetPluralForms should succeed`) {
+		return
+	}
+
+	po.Set("", "Hello", "", []string{"Привет"})
+	po.Set("", "%d file", "%d files", []string{"%d файл", "%d файла", "%d файлов"})
+	po.Set("menu", "Open", "", []string{"Открыть"})
+
+	assert.Equal(t, "Привет", po.Get("Hello"))
+	assert.Equal(t, "Открыть", po.GetC("Open", "menu"))
+	assert.Equal(t, "1 файл", po.GetN("%d file", "%d files", 1, 1))
+	assert.Equal(t, "3 файла", po.GetN("%d file", "%d files", 3, 3))
+	assert.Equal(t, "5 файлов", po.GetN("%d file", "%d files", 5, 5))
+	assert.Empty(t, po.Validate(), `Validate should succeed`)
+
+	named := NewPo(WithNamedPlaceholders(true))
+	named.Set("", "Hello", "", []string{"Hi"})
+	assert.Equal(t, "Hi", named.Get("Hello"), `options should be applied`)
+	assert.True(t, named.namedPlaceholders, `options should be applied`)
+}
+
+func TestPoSetPluralFunc(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3; plural=(n == 1 ? 0 : 1);\n"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "%d form0"
+msgstr[1] "%d form1"
+msgstr[2] "%d form2"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "5 form1", po.GetN("One file", "%d files", 5, 5))
+
+	var calls []int
+	po.SetPluralFunc(func(n int) int {
+		calls = append(calls, n)
+		switch {
+		case n == 1:
+			return 0
+		case n < 10:
+			return 1
+		case n < 100:
+			return 2
+		}
+		return 3
+	})
+	assert.Equal(t, "1 form0", po.GetN("One file", "%d files", 1, 1))
+	assert.Equal(t, "5 form1", po.GetN("One file", "%d files", 5, 5))
+	assert.Equal(t, "50 form2", po.GetN("One file", "%d files", 50, 50))
+	assert.Equal(t, "500 form0", po.GetN("One file", "%d files", 500, 500), `out of range index should select the first form`)
+	assert.Equal(t, "-50 form2", po.GetN("One file", "%d files", -50, -50))
+	assert.Equal(t, []int{1, 5, 50, 500, 50}, calls, `negative counts should be passed as their absolute value`)
+	assert.Equal(t, 2, po.PluralIndex(50))
+	assert.Equal(t, 2, po.Clone().PluralIndex(50), `function should be copied by Clone`)
+
+	po.SetPluralFunc(nil)
+	assert.Equal(t, "50 form1", po.GetN("One file", "%d files", 50, 50), `nil should restore the formula`)
+
+	po.Seal()
+	po.SetPluralFunc(func(int) int { return 2 })
+	assert.Equal(t, "50 form1", po.GetN("One file", "%d files", 50, 50), `sealed Po should not be modified`)
+}
+
+func TestPoPluralFormError(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n != ;\n"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed in non-strict mode`) {
+		return
+	}
+	assert.Error(t, po.PluralFormError(), `broken formula should be recorded`)
+	assert.Equal(t, "Un fichier", po.GetN("One file", "%d files", 2), `first form should be selected`)
+	assert.Error(t, po.Clone().PluralFormError(), `error should be copied by Clone`)
+
+	_, err = NewParser(WithStrictParsing(true)).ParseString(str)
+	assert.Error(t, err, `ParseString should fail in strict mode`)
+
+	po.SetHeader("Plural-Forms", "nplurals=2; plural=(n > 1);")
+	assert.NoError(t, po.PluralFormError(), `valid formula should clear the error`)
+	assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2))
+
+	po.SetHeader("Plural-Forms", "nplurals=2; plural=(n > ;")
+	assert.Error(t, po.PluralFormError(), `SetHeader should record the error`)
+	if assert.NoError(t, po.SetPluralForms(2, "(n > 1)"), `SetPluralForms should succeed`) {
+		assert.NoError(t, po.PluralFormError(), `SetPluralForms should clear the error`)
+	}
+
+	po, err = NewParser().ParseString(`msgid "Hello"` + "\n" + `msgstr "Bonjour"`)
+	if assert.NoError(t, err, `ParseString should succeed`) {
+		assert.NoError(t, po.PluralFormError(), `default formula should compile`)
+	}
+}
+
+func TestPoEmptyMsgstr(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Untranslated %s"
+msgstr ""
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d fichier"
+msgstr[1] ""
+
+msgctxt "menu"
+msgid "Open"
+msgstr ""
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "Untranslated foo", po.Get("Untranslated %s", "foo"), `empty msgstr should fall back to msgid`)
+	v, ok := po.TryGet("Untranslated %s", "foo")
+	assert.False(t, ok, `empty msgstr should not count as translated`)
+	assert.Equal(t, "Untranslated foo", v)
+
+	assert.Equal(t, "1 fichier", po.GetN("%d file", "%d files", 1, 1))
+	assert.Equal(t, "2 files", po.GetN("%d file", "%d files", 2, 2), `empty plural form should fall back to msgid_plural`)
+	assert.Equal(t, "Open", po.GetC("Open", "menu"), `empty msgstr should fall back to msgid`)
+}
+
+func TestPoGetAll(t *testing.T) {
+	po, err := NewParser().ParseString(`
+msgid "Name"
+msgstr "Nom"
+
+msgid "Size"
+msgstr "Taille"
+
+msgid "Empty"
+msgstr ""
+`)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	ids := []string{"Name", "Unknown", "Size", "Empty", "100%"}
+	assert.Equal(t, []string{"Nom", "Unknown", "Taille", "Empty", "100%"}, po.GetAll(ids))
+	assert.Empty(t, po.GetAll(nil))
+}
+
+func TestPoSeal(t *testing.T) {
+	po := NewPo()
+	po.Set("", "Hello", "", []string{"Bonjour"})
+	assert.False(t, po.Sealed(), `new Po should not be sealed`)
+
+	po.Seal()
+	assert.True(t, po.Sealed(), `Po should be sealed`)
+	assert.Equal(t, "Bonjour", po.Get("Hello"))
+
+	po.Set("", "Hello", "", []string{"Salut"})
+	po.Set("", "Bye", "", []string{"Au revoir"})
+	po.Delete("", "Hello")
+	assert.Equal(t, "Bonjour", po.Get("Hello"), `Set and Delete should have no effect`)
+	assert.Equal(t, "Bye", po.Get("Bye"), `Set and Delete should have no effect`)
+	assert.Error(t, po.SetPluralForms(2, "(n != 1)"), `SetPluralForms should fail`)
+
+	c := po.Clone()
+	assert.False(t, c.Sealed(), `clone should not be sealed`)
+	c.Set("", "Hello", "", []string{"Salut"})
+	assert.Equal(t, "Salut", c.Get("Hello"))
+
+	// Lookups while sealing must be safe
+	po = NewPo()
+	po.Set("", "Hello", "", []string{"Bonjour"})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i == 5 {
+				po.Seal()
+				return
+			}
+			assert.Equal(t, "Bonjour", po.Get("Hello"))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPoNamedPlaceholders(t *testing.T) {
+	str := `
+msgid "Hello, %{name}. You have %{count} messages"
+msgstr "%{count} messages for %{name}"
+
+msgid "Positional %s"
+msgstr "Translated positional %s"
+`
+
+	po, err := NewParser(WithNamedPlaceholders(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	// msgids are kept in variables, as go vet does not know about
+	// named placeholders
+	hello := "Hello, %{name}. You have %{count} messages"
+	missing := "Missing %{unknown} for %{name}"
+
+	vars := map[string]interface{}{"name": "John", "count": 3}
+	assert.Equal(t, "3 messages for John", po.Get(hello, vars))
+	assert.Equal(t, "Missing %{unknown} for John", po.Get(missing, vars), `unknown keys should be left untouched`)
+	assert.Equal(t, "Translated positional foo", po.Get("Positional %s", "foo"), `non-map arguments should use fmt syntax`)
+}
+
+func TestPoSafeFormat(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=n != 1;\n"
+
+msgid "%s has %d items"
+msgstr "%d items belong to %s"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%s file"
+msgstr[1] "%s files"
+
+msgid "Hello, %s"
+msgstr "Hi, %s"
+
+msgid "Score: %d%%!"
+msgstr "Résultat : %d%%!"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Contains(t, po.Get("%s has %d items", "John", 3), "%!d(string=John)", `without safe format, output is garbled`)
+
+	var mismatches []string
+	po, err = NewParser(
+		WithSafeFormat(true),
+		WithFormatMismatchHandler(func(msgid, msgstr string) {
+			mismatches = append(mismatches, msgid+" => "+msgstr)
+		}),
+	).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "John has 3 items", po.Get("%s has %d items", "John", 3))
+	assert.Equal(t, "3 files", po.GetN("%d file", "%d files", 3, 3))
+	assert.Equal(t, "Hi, John", po.Get("Hello, %s", "John"))
+	// The output contains "%!", which is not an error
+	score := "Score: %d%%!"
+	assert.Equal(t, "Résultat : 100%!", po.Get(score, 100))
+	assert.Equal(t, []string{"%s has %d items => %d items belong to %s", "%d files => %s files"}, mismatches)
+}
+
+func TestPoNoFormatWithoutVars(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=n != 1;\n"
+
+msgid "Progress: 100%"
+msgstr "Progression : 100%"
+
+msgid "One percent"
+msgid_plural "Many percents"
+msgstr[0] "1%"
+msgstr[1] "50%"
+
+msgctxt "Ctx"
+msgid "Done: 100%"
+msgstr "Terminé : 100%"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	// msgids are kept in variables, otherwise go vet complains
+	// about the '%' characters
+	progress, untranslated, done := "Progress: 100%", "Untranslated: 100%", "Done: 100%"
+
+	assert.Equal(t, "Progression : 100%", po.Get(progress))
+	assert.Equal(t, "Untranslated: 100%", po.Get(untranslated))
+	assert.Equal(t, "50%", po.GetN("One percent", "Many percents", 2))
+	assert.Equal(t, "Terminé : 100%", po.GetC(done, "Ctx"))
+	assert.Equal(t, "Untranslated: 100%", NullLocale{}.Get(untranslated))
+}
+
+func TestParseIndexedSingular(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Language: ar\n"
+"Plural-Forms: nplurals=6; plural=(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5);\n"
+
+msgid "Hello"
+msgstr[0] "مرحبا"
+
+msgid "Multi"
+msgstr[0] ""
+"سطر "
+"متعدد"
+
+msgid "Untranslated"
+msgstr[0] ""
+
+msgctxt "menu"
+msgid "Open"
+msgstr[0] "فتح"
+`
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, "msgstr[0] without msgid_plural should be accepted") {
+		return
+	}
+
+	assert.Equal(t, "مرحبا", po.Get("Hello"))
+	assert.Equal(t, "سطر متعدد", po.Get("Multi"))
+	assert.Equal(t, "Untranslated", po.Get("Untranslated"))
+	assert.Equal(t, "فتح", po.GetC("Open", "menu"))
+
+	// The only form is used for any n, even though the formula of the
+	// language maps 1 to the second form
+	for _, n := range []int{0, 1, 2, 11} {
+		assert.Equal(t, "مرحبا", po.GetN("Hello", "Hellos", n), "GetN for "+strconv.Itoa(n))
+		assert.Equal(t, "فتح", po.GetNC("Open", "Open", n, "menu"), "GetNC for "+strconv.Itoa(n))
+	}
+	assert.Equal(t, "Untranslated", po.GetN("Untranslated", "Untranslated", 1))
+
+	// Locale.Get goes through GetN with n = 1
+	l := NewLocale("ar", WithSource(NewMapSource(map[string][]byte{
+		"ar/LC_MESSAGES/default.po": []byte(str),
+	})))
+	if assert.NoError(t, l.AddDomain("default"), "AddDomain should succeed") {
+		assert.Equal(t, "مرحبا", l.Get("Hello"))
+		assert.Equal(t, "Untranslated", l.Get("Untranslated"))
+		assert.Equal(t, "فتح", l.GetC("Open", "menu"))
+	}
+
+	m, ok := po.Message("Hello")
+	if assert.True(t, ok, "Message should find the entry") {
+		assert.Equal(t, []string{"مرحبا"}, m.Strings)
+		assert.Equal(t, "", m.PluralID)
+	}
+}
+
+func TestPoGetRaw(t *testing.T) {
+	po, err := NewParser(WithNamedPlaceholders(true)).ParseString(`
+msgid "100%"
+msgstr "100 %"
+
+msgid "%s of %d"
+msgstr "%d sur %s"
+
+msgid "%{name} is here"
+msgstr "%{name} est là"
+
+msgid "untranslated"
+msgstr ""
+
+msgctxt "menu"
+msgid "Save %"
+msgstr "Enregistrer %"
+`)
+	if !assert.NoError(t, err, "ParseString should succeed") {
+		return
+	}
+
+	assert.Equal(t, "100 %", po.GetRaw("100%"))
+	assert.Equal(t, "%d sur %s", po.GetRaw("%s of %d"), "verbs are left untouched")
+	assert.Equal(t, "%{name} est là", po.GetRaw("%{name} is here"), "placeholders are left untouched")
+	assert.Equal(t, "untranslated", po.GetRaw("untranslated"))
+	assert.Equal(t, "not found %d", po.GetRaw("not found %d"))
+	assert.Equal(t, "Enregistrer %", po.GetRaw("menu\x04Save %"))
+}
+
+func TestPoFprintf(t *testing.T) {
+	str := `
+msgid "Hello, %s"
+msgstr "Bonjour, %s"
+
+msgid "100%"
+msgstr "100 %"
+
+msgid "%s has %d items"
+msgstr "%d éléments pour %s"
+
+msgid "untranslated %s"
+msgstr ""
+
+msgctxt "menu"
+msgid "Open %s"
+msgstr "Ouvrir %s"
+`
+	parsers := map[string]*Parser{
+		"default":          NewParser(),
+		"safe format":      NewParser(WithSafeFormat(true)),
+		"missing handler":  NewParser(WithMissingHandler(func(id string) string { return "missing: " + id })),
+		"context fallback": NewParser(WithContextFallback(true)),
+	}
+	for name, p := range parsers {
+		po, err := p.ParseString(str)
+		if !assert.NoError(t, err, "ParseString should succeed") {
+			return
+		}
+
+		cases := [][]interface{}{
+			{"Hello, %s", "John"},
+			{"100%"},
+			{"%s has %d items", "John", 3},
+			{"untranslated %s", "John"},
+			{"not found %s", "John"},
+			{"menu\x04Open %s", "file"},
+		}
+		for _, c := range cases {
+			var buf bytes.Buffer
+			n, err := po.Fprintf(&buf, c[0].(string), c[1:]...)
+			assert.NoError(t, err, name+": Fprintf should succeed")
+			assert.Equal(t, po.Get(c[0].(string), c[1:]...), buf.String(), name+": Fprintf should match Get for "+c[0].(string))
+			assert.Equal(t, buf.Len(), n, name+": Fprintf should return the number of bytes written")
+		}
+
+		for _, ctx := range []string{"menu", "other"} {
+			var buf bytes.Buffer
+			_, err := po.FprintfC(&buf, "Open %s", ctx, "file")
+			assert.NoError(t, err, name+": FprintfC should succeed")
+			assert.Equal(t, po.GetC("Open %s", ctx, "file"), buf.String(), name+": FprintfC should match GetC in context "+ctx)
+		}
+	}
+}
+
+func TestPoIndexedVerbs(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Language: de\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "%s has %d files"
+msgstr "%[2]d Dateien gehören %[1]s"
+
+msgid "%s deleted %d file"
+msgid_plural "%s deleted %d files"
+msgstr[0] "Eine Datei wurde von %[1]s gelöscht"
+msgstr[1] "%[2]d Dateien wurden von %[1]s gelöscht"
+`
+	for _, safe := range []bool{false, true} {
+		po, err := NewParser(WithSafeFormat(safe)).ParseString(str)
+		if !assert.NoError(t, err, "ParseString should succeed") {
+			return
+		}
+
+		assert.Equal(t, "3 Dateien gehören John", po.Get("%s has %d files", "John", 3), "values are reordered")
+		// Unused values are not reported as extra when indexes are used
+		assert.Equal(t, "Eine Datei wurde von John gelöscht", po.GetN("%s deleted %d file", "%s deleted %d files", 1, "John", 1), "singular form uses one value")
+		assert.Equal(t, "2 Dateien wurden von John gelöscht", po.GetN("%s deleted %d file", "%s deleted %d files", 2, "John", 2), "plural form reorders values")
+	}
+}
+
+func TestFormatVerbs(t *testing.T) {
+	assert.Empty(t, FormatVerbs("no verbs"))
+	assert.Empty(t, FormatVerbs("100%% done, 50%"))
+	assert.Equal(t, []string{"%d", "%-5.2f", "%[1]s"}, FormatVerbs("%d items at %-5.2f by %[1]s"))
+	assert.Equal(t, []string{"%*d", "%.*f", "%[2]*.[1]*v", "%+q"}, FormatVerbs("%*d %.*f %[2]*.[1]*v %+q"))
+	assert.Equal(t, []string{"%s", "%d"}, FormatVerbs("%s — %d%%"))
+
+	assert.True(t, formatsMatch("%s has %d items", "%s a %d éléments"))
+	assert.True(t, formatsMatch("%s has %d items", "%[2]d items belong to %[1]s"))
+	assert.True(t, formatsMatch("%s deleted %d files", "Deleted by %[1]s"), `values may be left out with indexes`)
+	assert.True(t, formatsMatch("%*d", "%*d"))
+	assert.False(t, formatsMatch("%s has %d items", "%d items belong to %s"))
+	assert.False(t, formatsMatch("%d files", "Files"), `values left out without indexes are reported`)
+	assert.False(t, formatsMatch("%d", "%d %d"))
+
+	// Comparing the verbs of the msgid and msgstr of each entry
+	po, err := NewParser().ParseString(`
+msgid "%s has %d items"
+msgstr "%s a %d éléments"
+
+msgid "%d files by %s"
+msgstr "%s: %d fichiers"
+`)
+	if !assert.NoError(t, err, "ParseString should succeed") {
+		return
+	}
+	var mismatches []string
+	po.EachSorted(func(m Message) bool {
+		if !assert.Len(t, m.Strings, 1) {
+			return false
+		}
+		if !assert.ObjectsAreEqual(FormatVerbs(m.ID), FormatVerbs(m.Strings[0])) {
+			mismatches = append(mismatches, m.ID)
+		}
+		return true
+	})
+	assert.Equal(t, []string{"%d files by %s"}, mismatches)
+}
+
+func TestEscapePercent(t *testing.T) {
+	assert.Equal(t, "100%%", EscapePercent("100%"))
+	assert.Equal(t, "no percent", EscapePercent("no percent"))
+
+	str := `
+msgid "%s: %d%% done"
+msgstr "%s : %d%% terminé"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "Upload : 50% terminé", po.Get("%s: %d%% done", "Upload", 50))
+	assert.Equal(t, "50% off: 10% done", po.Get(EscapePercent("50% off")+": %d%% done", 10))
+}
+
+func TestPoCLDRPlurals(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Language: ru_RU\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] "%d файла"
+msgstr[2] "%d файлов"
+`
+
+	po, err := NewParser(WithCLDRPlurals(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	expected := map[int]string{
+		1:   "1 файл",
+		2:   "2 файла",
+		5:   "5 файлов",
+		11:  "11 файлов",
+		21:  "21 файл",
+		22:  "22 файла",
+		112: "112 файлов",
+	}
+	for n, s := range expected {
+		assert.Equal(t, s, po.GetN("%d file", "%d files", n, n))
+	}
+
+	rule := lookupCLDRRule("ar")
+	if assert.NotNil(t, rule, `rule for ar should exist`) {
+		for n, idx := range map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 10: 3, 11: 4, 99: 4, 100: 5, 102: 5, 103: 3} {
+			assert.Equal(t, idx, rule.index(n), `ar index`)
+		}
+	}
+
+	rule = lookupCLDRRule("ja")
+	if assert.NotNil(t, rule, `rule for ja should exist`) {
+		assert.Equal(t, 0, rule.index(100))
+	}
+
+	assert.Nil(t, lookupCLDRRule("xx"), `rule for unknown language should not exist`)
+}
+
+func TestPoGetCategory(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] "%d файла"
+msgstr[2] "%d файлов"
+
+msgid "%d folder"
+msgid_plural "%d folders"
+msgstr[0] "%d папка"
+msgstr[1] ""
+msgstr[2] ""
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, "21 файл", po.GetCategory("%d file", PluralOne, 21))
+	assert.Equal(t, "3 файла", po.GetCategory("%d file", PluralFew, 3))
+	assert.Equal(t, "5 файлов", po.GetCategory("%d file", PluralMany, 5))
+	assert.Equal(t, "7 files", po.GetCategory("%d file", PluralOther, 7), `unused form should fall back to msgid_plural`)
+	assert.Equal(t, "0 files", po.GetCategory("%d file", PluralZero, 0), `unknown category should fall back to msgid_plural`)
+	assert.Equal(t, "1 папка", po.GetCategory("%d folder", PluralOne, 1))
+	assert.Equal(t, "3 folders", po.GetCategory("%d folder", PluralFew, 3), `untranslated form should fall back to msgid_plural`)
+	assert.Equal(t, "1 thing", po.GetCategory("%d thing", PluralOne, 1), `missing entry should fall back to msgid`)
+
+	po.SetHeader("Language", "xx")
+	assert.Equal(t, "5 files", po.GetCategory("%d file", PluralMany, 5), `unknown language should fall back to msgid_plural`)
+	assert.Equal(t, "1 file", po.GetCategory("%d file", PluralOne, 1), `unknown language should fall back to msgid`)
+}
+
+func TestPoDefaultPluralForms(t *testing.T) {
+	// No headers at all: English rules are assumed
+	str := `
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d file (translated)"
+msgstr[1] "%d files (translated)"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "1 file (translated)", po.GetN("%d file", "%d files", 1, 1))
+	assert.Equal(t, "0 files (translated)", po.GetN("%d file", "%d files", 0, 0))
+	assert.Equal(t, "2 files (translated)", po.GetN("%d file", "%d files", 2, 2))
+
+	// Language without Plural-Forms
+	str = `
+msgid ""
+msgstr ""
+"Language: fr\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d fichier"
+msgstr[1] "%d fichiers"
+`
+
+	po, err = NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "0 fichier", po.GetN("%d file", "%d files", 0, 0))
+	assert.Equal(t, "1 fichier", po.GetN("%d file", "%d files", 1, 1))
+	assert.Equal(t, "2 fichiers", po.GetN("%d file", "%d files", 2, 2))
+
+	// The rule of the Language header is used for each form
+	for _, tc := range []struct {
+		lang  string
+		forms []string
+		cases map[int]int // n -> expected form
+	}{
+		{"ru", []string{"0", "1", "2"}, map[int]int{1: 0, 21: 0, 2: 1, 24: 1, 5: 2, 11: 2, 12: 2, 111: 2}},
+		{"pl", []string{"0", "1", "2"}, map[int]int{1: 0, 2: 1, 22: 1, 0: 2, 5: 2, 12: 2, 21: 2}},
+		{"ar", []string{"0", "1", "2", "3", "4", "5"}, map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 110: 3, 11: 4, 99: 4, 100: 5, 102: 5}},
+		{"ja_JP", []string{"0"}, map[int]int{0: 0, 1: 0, 2: 0, 100: 0}},
+	} {
+		str := "msgid \"\"\nmsgstr \"\"\n\"Language: " + tc.lang + "\\n\"\n\nmsgid \"a\"\nmsgid_plural \"b\"\n"
+		for i, form := range tc.forms {
+			str += "msgstr[" + strconv.Itoa(i) + "] \"" + form + "\"\n"
+		}
+
+		po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+		if !assert.NoError(t, err, `ParseString should succeed for `+tc.lang) {
+			return
+		}
+		assert.Equal(t, len(tc.forms), po.NPlurals(), `nplurals for `+tc.lang)
+		for n, form := range tc.cases {
+			assert.Equal(t, strconv.Itoa(form), po.GetN("a", "b", n), `form for `+tc.lang+` with n = `+strconv.Itoa(n))
+		}
+	}
+
+	// Changing the language also changes the rule, unless the catalog
+	// has a Plural-Forms header
+	po = NewPo()
+	po.SetHeader("Language", "ru")
+	assert.Equal(t, 3, po.NPlurals(), `nplurals should follow the Language header`)
+	assert.Equal(t, 1, po.PluralIndex(3))
+	po.SetHeader("Plural-Forms", "nplurals=2; plural=(n != 1);")
+	po.SetHeader("Language", "ja")
+	assert.Equal(t, 2, po.NPlurals(), `Plural-Forms header should take precedence`)
+
+	assert.Equal(t, fallbackPluralForms, lookupPluralForms("xx"))
+	assert.Equal(t, defaultPluralForms["pt_BR"], lookupPluralForms("pt-br"))
+	assert.Equal(t, defaultPluralForms["ru"], lookupPluralForms("ru_RU.UTF-8"))
+}
+
+func TestPoPluralIndex(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	expected := map[int]int{0: 2, 1: 0, 2: 1, 4: 1, 5: 2, 12: 2, 21: 2, 22: 1, 112: 2, 122: 1}
+	for n, idx := range expected {
+		assert.Equal(t, idx, po.PluralIndex(n), `PluralIndex should match the Polish rule`)
+	}
+}
+
+func TestPluralFormsBoundaries(t *testing.T) {
+	const maxInt = int(^uint(0) >> 1)
+	const minInt = -maxInt - 1
+
+	ar := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=6; plural=(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5);\n"
+`
+	po, err := NewParser().ParseString(ar)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	for n, idx := range map[int]int{
+		0: 0, 1: 1, 2: 2, 3: 3, 10: 3, 11: 4, 99: 4, 100: 5, 101: 5, 102: 5, 103: 3, 111: 4,
+		-1: 1, -2: 2, -11: 4, -100: 5,
+		maxInt: 3, minInt: 3,
+	} {
+		assert.Equal(t, idx, po.pluralForm(n), `ar`)
+	}
+
+	ru := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+`
+	po, err = NewParser().ParseString(ru)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	for n, idx := range map[int]int{
+		0: 2, 1: 0, 2: 1, 4: 1, 5: 2, 11: 2, 12: 2, 14: 2, 19: 2, 20: 2, 21: 0, 22: 1, 24: 1, 25: 2,
+		111: 2, 121: 0, 1001: 0,
+		-1: 0, -2: 1, -5: 2, -21: 0,
+		maxInt: 2, minInt: 2,
+	} {
+		assert.Equal(t, idx, po.pluralForm(n), `ru`)
+	}
+}
+
+func TestPoPluralAccessors(t *testing.T) {
+	po, err := NewParser().ParseString(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n==2 ? 1 : 2);\n"
+`)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, 3, po.NPlurals())
+	assert.Equal(t, "(n==1 ? 0 : n==2 ? 1 : 2)", po.PluralFormula())
+
+	po, err = NewParser().ParseString(`
+msgid ""
+msgstr ""
+"Language: ja\n"
+`)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "ja", po.Language())
+	assert.Equal(t, 1, po.NPlurals(), `default for the language should be reported`)
+	assert.Equal(t, "0", po.PluralFormula(), `default for the language should be reported`)
+
+	po = NewPo()
+	assert.Equal(t, 0, po.NPlurals())
+	assert.Equal(t, "", po.PluralFormula())
+	if assert.NoError(t, po.SetPluralForms(2, "(n > 1)"), `SetPluralForms should succeed`) {
+		assert.Equal(t, 2, po.NPlurals())
+		assert.Equal(t, "(n > 1)", po.PluralFormula())
+	}
+}
+
+func TestPluralFormsOutOfRange(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n == 1 ? 0 : n == 2 ? 2 : n == 3 ? -1 : 1);\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d fichier"
+msgstr[1] "%d fichiers"
+msgstr[2] "%d out of range"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	assert.Equal(t, 0, po.PluralIndex(1))
+	assert.Equal(t, 0, po.PluralIndex(2), `index equal to nplurals should be rejected`)
+	assert.Equal(t, 0, po.PluralIndex(3), `negative index should be rejected`)
+	assert.Equal(t, 1, po.PluralIndex(4))
+	assert.Equal(t, "2 fichier", po.GetN("%d file", "%d files", 2, 2))
+}
+
+func TestPoContextFallbackPlural(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=n != 1;\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d file (no context)"
+msgstr[1] "%d files (no context)"
+
+msgctxt "Ctx"
+msgid "%d folder"
+msgid_plural "%d folders"
+msgstr[0] "%d folder (Ctx)"
+msgstr[1] "%d folders (Ctx)"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "2 files", po.GetNC("%d file", "%d files", 2, "Ctx", 2), `no fallback by default`)
+
+	po, err = NewParser(WithContextFallback(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "2 files (no context)", po.GetNC("%d file", "%d files", 2, "Ctx", 2), `fallback to translation without context`)
+	assert.Equal(t, "2 folders (Ctx)", po.GetNC("%d folder", "%d folders", 2, "Ctx", 2), `context entry takes precedence`)
+	assert.Equal(t, "2 things", po.GetNC("%d thing", "%d things", 2, "Ctx", 2), `source string when nothing matches`)
+}
+
+func TestPoContextFallback(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+
+msgid "Open"
+msgstr "Open (no context)"
+
+msgctxt "Menu"
+msgid "Close"
+msgstr "Close (Menu)"
+
+msgctxt "Dialog"
+msgid "Open"
+msgstr "Open (Dialog)"
+`
+
+	po, err := NewParser().ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "Open", po.GetC("Open", "Menu"), `no fallback by default`)
+
+	po, err = NewParser(WithContextFallback(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "Open (Dialog)", po.GetC("Open", "Dialog"), `context entry takes precedence`)
+	assert.Equal(t, "Open (no context)", po.GetC("Open", "Menu"), `fallback to translation without context`)
+	assert.Equal(t, "Open (no context)", po.GetC("Open", "Unknown"), `fallback for unknown context`)
+	assert.Equal(t, "Close", po.GetC("Close", "Dialog"), `source string when nothing matches`)
+}
+
+func TestParseReader(t *testing.T) {
+	str := `
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "One apple"
+msgid_plural "%d apples"
+msgstr[0] "A single apple"
+msgstr[1] "A lot of "
+"apples: %d"
+`
+
+	po, err := NewParser().ParseReader(iotest.OneByteReader(strings.NewReader(str)))
+	if !assert.NoError(t, err, `ParseReader should succeed`) {
+		return
+	}
+	assert.Equal(t, "A single apple", po.GetN("One apple", "%d apples", 1))
+	assert.Equal(t, "A lot of apples: 5", po.GetN("One apple", "%d apples", 5, 5))
+
+	_, err = NewParser().ParseReader(iotest.TimeoutReader(strings.NewReader(str)))
+	assert.Error(t, err, `read errors should be reported`)
+
+	_, err = NewParser().ParseReader(strings.NewReader(`msgid "` + strings.Repeat("x", maxLineSize) + `"`))
+	assert.Error(t, err, `lines that are too long should be reported`)
+	po, err = NewParser().ParseString(`msgid "` + strings.Repeat("x", maxLineSize) + `"` + "\nmsgstr \"long\"\n")
+	if assert.NoError(t, err, `Parse should accept lines of any length`) {
+		assert.Equal(t, "long", po.Get(strings.Repeat("x", maxLineSize)))
+	}
+}
+
+// cancelAfterContext is canceled after Done has been called n times
+type cancelAfterContext struct {
+	context.Context
+	cancel func()
+	n      int
+}
+
+func (c *cancelAfterContext) Done() <-chan struct{} {
+	c.n--
+	if c.n < 0 {
+		c.cancel()
+	}
+	return c.Context.Done()
+}
+
+func TestParseContext(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&buf, "msgid \"msg%d\"\nmsgstr \"str%d\"\n\n", i, i)
+	}
+	data := buf.Bytes()
+
+	po, err := NewParser().ParseContext(context.Background(), data)
+	if !assert.NoError(t, err, `ParseContext should succeed`) {
+		return
+	}
+	assert.Equal(t, "str9999", po.Get("msg9999"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	po, err = NewParser().ParseContext(ctx, data)
+	assert.Equal(t, context.Canceled, err, `canceled context should abort parsing`)
+	assert.Nil(t, po, `no catalog should be returned`)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	cctx := &cancelAfterContext{Context: ctx, cancel: cancel, n: 3}
+	_, err = NewParser().ParseContext(cctx, data)
+	assert.Equal(t, context.Canceled, err, `cancellation while parsing should abort parsing`)
+	assert.Equal(t, -1, cctx.n, `parsing should stop at the first check after cancellation`)
+}
+
+func TestParseLineEndings(t *testing.T) {
+	lines := []string{
+		`msgid ""`,
+		`msgstr ""`,
+		`"Language: fr\n"`,
+		`"Plural-Forms: nplurals=2; plural=(n > 1);\n"`,
+		``,
+		`msgctxt "menu"`,
+		`msgid "Open"`,
+		`msgstr "Ouvrir"`,
+		``,
+		`msgid "One file"`,
+		`msgid_plural "%d files"`,
+		`msgstr[0] "Un fichier"`,
+		`msgstr[1] "%d "`,
+		`"fichiers"`,
+	}
+
+	for _, eol := range []string{"\n", "\r\n", "\r"} {
+		str := strings.Join(lines, eol) + eol
+		for _, strict := range []bool{false, true} {
+			po, err := NewParser(WithStrictParsing(strict)).ParseString(str)
+			if !assert.NoError(t, err, "ParseString should succeed for "+strconv.Quote(eol)) {
+				return
+			}
+			assert.Equal(t, "fr", po.language, "language for "+strconv.Quote(eol))
+			assert.Equal(t, "Ouvrir", po.GetC("Open", "menu"), "GetC for "+strconv.Quote(eol))
+			assert.Equal(t, "Un fichier", po.GetN("One file", "%d files", 1), "GetN(1) for "+strconv.Quote(eol))
+			assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2), "GetN(2) for "+strconv.Quote(eol))
+		}
+
+		// Make sure that "\r\n" is not split when it straddles reads
+		po, err := NewParser().ParseReader(iotest.OneByteReader(strings.NewReader(str)))
+		if !assert.NoError(t, err, "ParseReader should succeed for "+strconv.Quote(eol)) {
+			return
+		}
+		assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2), "ParseReader for "+strconv.Quote(eol))
+
+		// Line numbers must not be affected by the line endings either
+		bad := strings.Join([]string{`msgid "a"`, `msgstr "b"`, `bogus`}, eol)
+		_, err = NewParser(WithStrictParsing(true)).ParseReader(iotest.OneByteReader(strings.NewReader(bad)))
+		var perr *ParseError
+		if assert.True(t, errors.As(err, &perr), "ParseError expected for "+strconv.Quote(eol)) {
+			assert.Equal(t, 3, perr.Line, "line number for "+strconv.Quote(eol))
+		}
+		_, err = NewParser(WithStrictParsing(true)).ParseString(bad)
+		perr = nil
+		if assert.True(t, errors.As(err, &perr), "ParseError expected for "+strconv.Quote(eol)) {
+			assert.Equal(t, 3, perr.Line, "line number for "+strconv.Quote(eol))
+		}
+	}
+}
+
+func TestParseBlankLines(t *testing.T) {
+	str := "\n\n\n" +
+		"msgid \"\"\n" +
+		"msgstr \"\"\n" +
+		"\"Language: fr\\n\"\n" +
+		"\n\n\n" +
+		"msgid \"A\"\n" +
+		"msgstr \"a\"\n" +
+		"\n" +
+		"\n" +
+		"msgid \"B\"\n" +
+		"\n" +
+		"msgstr \"b\"\n" +
+		"\n\n"
+
+	parsers := map[string]func(string) (*Po, error){
+		"Parse": NewParser(WithStrictParsing(true)).ParseString,
+		"ParseReader": func(s string) (*Po, error) {
+			return NewParser(WithStrictParsing(true)).ParseReader(iotest.OneByteReader(strings.NewReader(s)))
+		},
+	}
+
+	for name, parse := range parsers {
+		for _, eol := range []string{"\n", "\r\n"} {
+			po, err := parse(strings.Replace(str, "\n", eol, -1))
+			if !assert.NoError(t, err, name+" should succeed for "+strconv.Quote(eol)) {
+				continue
+			}
+			assert.Equal(t, "fr", po.Language(), name+": headers after leading blank lines")
+			assert.Equal(t, "a", po.Get("A"), name+": entry after consecutive blank lines")
+			assert.Equal(t, "b", po.Get("B"), name+": blank line inside an entry")
+		}
+	}
+}
+
+func TestParseNoTrailingNewline(t *testing.T) {
+	testcases := []struct {
+		name  string
+		str   string
+		check func(*Po)
+	}{
+		{
+			name: "msgstr",
+			str:  "msgid \"A\"\nmsgstr \"a\"\n\nmsgid \"Last\"\nmsgstr \"last\"",
+			check: func(po *Po) {
+				assert.Equal(t, "a", po.Get("A"))
+				assert.Equal(t, "last", po.Get("Last"), `last entry should be flushed`)
+			},
+		},
+		{
+			name: "plural",
+			str:  "msgid \"One file\"\nmsgid_plural \"%d files\"\nmsgstr[0] \"Un fichier\"\nmsgstr[1] \"%d fichiers\"",
+			check: func(po *Po) {
+				assert.Equal(t, "2 fichiers", po.GetN("One file", "%d files", 2, 2), `last form should be flushed`)
+			},
+		},
+		{
+			name: "continuation",
+			str:  "msgctxt \"menu\"\nmsgid \"Open\"\nmsgstr \"Ouv\"\n\"rir\"",
+			check: func(po *Po) {
+				assert.Equal(t, "Ouvrir", po.GetC("Open", "menu"), `last continuation line should be flushed`)
+			},
+		},
+		{
+			name: "obsolete",
+			str:  "msgid \"A\"\nmsgstr \"a\"\n\n#~ msgid \"Old\"\n#~ msgstr \"old\"",
+			check: func(po *Po) {
+				if assert.Len(t, po.ObsoleteMessages(), 1, `obsolete entry should be flushed`) {
+					assert.Equal(t, []string{"old"}, po.ObsoleteMessages()[0].Strings)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		po, err := NewParser(WithStrictParsing(true)).ParseString(tc.str)
+		if assert.NoError(t, err, `ParseString should succeed for `+tc.name) {
+			tc.check(po)
+		}
+
+		po, err = NewParser(WithStrictParsing(true)).ParseReader(iotest.OneByteReader(strings.NewReader(tc.str)))
+		if assert.NoError(t, err, `ParseReader should succeed for `+tc.name) {
+			tc.check(po)
+		}
+	}
+}
+
+func TestParseBOM(t *testing.T) {
+	str := "\xef\xbb\xbf" + `msgid ""
+msgstr ""
+"Language: ja\n"
+
+msgid "Hello"
+msgstr "こんにちは"
+`
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	assert.Equal(t, "ja", po.language, `headers should be parsed`)
+	assert.Equal(t, "こんにちは", po.Get("Hello"))
+
+	po, err = NewParser().ParseReader(strings.NewReader("\xef\xbb\xbfmsgid \"Hello\"\nmsgstr \"Hi\"\n"))
+	if !assert.NoError(t, err, `ParseReader should succeed`) {
+		return
+	}
+	assert.Equal(t, "Hi", po.Get("Hello"), `msgid on the first line should be parsed`)
+}
+
+func BenchmarkParseLargeCatalog(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteString("\nmsgid \"\"\nmsgstr \"\"\n\"Plural-Forms: nplurals=2; plural=(n != 1);\\n\"\n")
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&buf, "\n#: src/file%d.c:%d\nmsgid \"Message number %d\"\nmsgstr \"Translated message number %d\"\n", i%100, i, i, i)
+		if i%10 == 0 {
+			fmt.Fprintf(&buf, "\nmsgctxt \"context %d\"\nmsgid \"Message number %d\"\nmsgstr \"Translated message number %d in context\"\n", i%50, i, i)
+		}
+	}
+	data := buf.Bytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewParser().Parse(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPoGetAll(b *testing.B) {
+	po := NewPo()
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("Message number %d", i)
+		po.Set("", ids[i], "", []string{fmt.Sprintf("Translated message number %d", i)})
+	}
+
+	b.Run("Get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, id := range ids {
+				_ = po.Get(id)
+			}
+		}
+	})
+	b.Run("GetAll", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = po.GetAll(ids)
+		}
+	})
+}
+
+func BenchmarkPoSealed(b *testing.B) {
+	for _, sealed := range []bool{false, true} {
+		po := NewPo()
+		for i := 0; i < 100; i++ {
+			po.Set("", fmt.Sprintf("Message number %d", i), "", []string{fmt.Sprintf("Translated message number %d", i)})
+		}
+		if sealed {
+			po.Seal()
+		}
+
+		name := "Locked"
+		if sealed {
+			name = "Sealed"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = po.Get("Message number 42")
+				}
+			})
+		})
+	}
+}
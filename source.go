@@ -1,8 +1,19 @@
 package gettext
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
 	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 func (f SourceFunc) ReadFile(s string) ([]byte, error) {
@@ -16,3 +27,304 @@ func NewFileSystemSource(dir string) *FileSystemSource {
 func (f FileSystemSource) ReadFile(s string) ([]byte, error) {
 	return ioutil.ReadFile(filepath.Join(f.root, s))
 }
+
+// Glob returns the names of the files under the root directory that
+// match the pattern, relative to the root directory
+func (f FileSystemSource) Glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(f.root, pattern))
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to match files with %s`, pattern)
+	}
+
+	for i, m := range matches {
+		rel, err := filepath.Rel(f.root, m)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to get the name of %s`, m)
+		}
+		matches[i] = rel
+	}
+	return matches, nil
+}
+
+// ReadDir returns the names of the entries of the given directory, which
+// is relative to the root directory
+func (f FileSystemSource) ReadDir(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(f.root, dir))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, nil
+}
+
+func (s NullSource) ReadFile(f string) ([]byte, error) {
+	return nil, &os.PathError{Op: "open", Path: f, Err: os.ErrNotExist}
+}
+
+// Glob always returns an empty list, as there are no files
+func (s NullSource) Glob(pattern string) ([]string, error) {
+	return nil, nil
+}
+
+// ReadDir always fails, as there are no directories
+func (s NullSource) ReadDir(dir string) ([]string, error) {
+	return nil, &os.PathError{Op: "open", Path: dir, Err: os.ErrNotExist}
+}
+
+// readDirNames returns the sorted names of the entries directly under
+// dir, given the names of all of the files. Directories are implied by
+// the names of the files that they contain
+func readDirNames(dir string, names []string) ([]string, error) {
+	dir = filepath.Clean(dir)
+	var prefix string
+	if dir != "." {
+		prefix = dir + string(filepath.Separator)
+	}
+
+	seen := make(map[string]struct{})
+	var entries []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		entry := name[len(prefix):]
+		if i := strings.IndexByte(entry, filepath.Separator); i > -1 {
+			entry = entry[:i]
+		}
+		if _, ok := seen[entry]; ok {
+			continue
+		}
+		seen[entry] = struct{}{}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: os.ErrNotExist}
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// globNames returns the names that match the pattern, in sorted order
+func globNames(pattern string, names []string) ([]string, error) {
+	var matches []string
+	for _, name := range names {
+		ok, err := filepath.Match(pattern, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to match files with %s`, pattern)
+		}
+		if ok {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// NewMapSource creates a new Source backed by the given map. The keys
+// must follow the same file name convention that is used against
+// FileSystemSource (e.g. "en/LC_MESSAGES/default.po").
+//
+// The map is used as is, so you should not modify it after passing it
+// to this function.
+func NewMapSource(files map[string][]byte) *MapSource {
+	return &MapSource{files: files}
+}
+
+func (s *MapSource) ReadFile(f string) ([]byte, error) {
+	data, ok := s.files[filepath.Clean(f)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: f, Err: os.ErrNotExist}
+	}
+	return data, nil
+}
+
+// Glob returns the names of the files that match the pattern
+func (s *MapSource) Glob(pattern string) ([]string, error) {
+	return globNames(pattern, s.names())
+}
+
+// ReadDir returns the names of the entries of the given directory, as
+// implied by the names of the files
+func (s *MapSource) ReadDir(dir string) ([]string, error) {
+	return readDirNames(dir, s.names())
+}
+
+func (s *MapSource) names() []string {
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, filepath.Clean(name))
+	}
+	return names
+}
+
+// NewZipSource creates a new Source that reads files from the given zip
+// archive. The entries of the archive are indexed by name, and are only
+// decompressed when they are read.
+func NewZipSource(r *zip.Reader) *ZipSource {
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		files[filepath.Clean(f.Name)] = f
+	}
+	return &ZipSource{files: files}
+}
+
+func (s *ZipSource) ReadFile(f string) ([]byte, error) {
+	zf, ok := s.files[filepath.Clean(f)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: f, Err: os.ErrNotExist}
+	}
+
+	r, err := zf.Open()
+	if err != nil {
+		return nil, errors.Wrapf(err, `zip: failed to open %s`, f)
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, `zip: failed to read %s`, f)
+	}
+	return data, nil
+}
+
+// Glob returns the names of the files in the archive that match the
+// pattern
+func (s *ZipSource) Glob(pattern string) ([]string, error) {
+	return globNames(pattern, s.names())
+}
+
+// ReadDir returns the names of the entries of the given directory of
+// the archive
+func (s *ZipSource) ReadDir(dir string) ([]string, error) {
+	return readDirNames(dir, s.names())
+}
+
+func (s *ZipSource) names() []string {
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	return names
+}
+
+// NewTarSource creates a new Source from the tar archive read from r.
+// As tar archives can only be read sequentially, all of the regular
+// files of the archive are read into memory, and served by a MapSource.
+// The names of the files follow the same convention as for
+// FileSystemSource (e.g. "en/LC_MESSAGES/default.po").
+func NewTarSource(r io.Reader) (*MapSource, error) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, `tar: failed to read archive`)
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, `tar: failed to read %s`, hdr.Name)
+		}
+		files[filepath.Clean(hdr.Name)] = data
+	}
+	return NewMapSource(files), nil
+}
+
+// gzipMagic is the header that all gzip-compressed data starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// NewGzipSource creates a new Source that reads files from src, and
+// transparently decompresses those that are gzip-compressed, as detected
+// by the gzip magic number. The file names are not changed, so a
+// compressed catalog must be stored under the usual name (e.g.
+// "en/LC_MESSAGES/default.po"), and not with a ".gz" extension.
+func NewGzipSource(src Source) *GzipSource {
+	return &GzipSource{src: src}
+}
+
+func (s *GzipSource) ReadFile(f string) ([]byte, error) {
+	data, err := s.src.ReadFile(f)
+	if err != nil || !bytes.HasPrefix(data, gzipMagic) {
+		return data, err
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, `gzip: failed to read %s`, f)
+	}
+	defer r.Close()
+
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, `gzip: failed to decompress %s`, f)
+	}
+	return data, nil
+}
+
+// ReadDir lists the directories of the wrapped Source, if it is a
+// DirSource. Otherwise, ErrReadDirNotSupported is returned
+func (s *GzipSource) ReadDir(dir string) ([]string, error) {
+	ds, ok := s.src.(DirSource)
+	if !ok {
+		return nil, ErrReadDirNotSupported
+	}
+	return ds.ReadDir(dir)
+}
+
+// Glob lists the files of the wrapped Source, if it is a GlobSource or a
+// DirSource. Otherwise, ErrGlobNotSupported is returned
+func (s *GzipSource) Glob(pattern string) ([]string, error) {
+	if gs, ok := s.src.(GlobSource); ok {
+		return gs.Glob(pattern)
+	}
+	if ds, ok := s.src.(DirSource); ok {
+		return dirGlobSource{ds}.Glob(pattern)
+	}
+	return nil, ErrGlobNotSupported
+}
+
+// Glob returns the names of the files that match the pattern, by reading
+// the directories that match each element of the pattern in turn.
+// Directories that cannot be read do not match, as with filepath.Glob
+func (s dirGlobSource) Glob(pattern string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	matches := []string{"."}
+	for _, elem := range strings.Split(pattern, "/") {
+		var next []string
+		for _, dir := range matches {
+			names, err := s.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, name := range names {
+				if ok, _ := path.Match(elem, name); ok {
+					next = append(next, path.Join(dir, name))
+				}
+			}
+		}
+		matches = next
+	}
+
+	sort.Strings(matches)
+	for i, m := range matches {
+		matches[i] = filepath.FromSlash(m)
+	}
+	return matches, nil
+}
@@ -0,0 +1,301 @@
+package gettext
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchingFileSystemSource(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "go-gettext-")
+	if !assert.NoError(t, err, `failed to create temporary directory`) {
+		return
+	}
+	defer os.RemoveAll(tmpdir)
+
+	dirname := filepath.Join(tmpdir, "en", "LC_MESSAGES")
+	if !assert.NoError(t, os.MkdirAll(dirname, os.ModePerm), `failed to create directory`) {
+		return
+	}
+
+	filename := filepath.Join(dirname, "default.po")
+	if !assert.NoError(t, ioutil.WriteFile(filename, []byte(`msgid "a"`), 0644), `failed to write file`) {
+		return
+	}
+
+	src := NewWatchingFileSystemSource(tmpdir, WithPollInterval(10*time.Millisecond))
+	defer src.Close()
+
+	data, err := src.ReadFile(filepath.Join("en", "LC_MESSAGES", "default.po"))
+	if !assert.NoError(t, err, `ReadFile should succeed`) {
+		return
+	}
+	assert.Equal(t, []byte(`msgid "a"`), data)
+
+	if !assert.NoError(t, ioutil.WriteFile(filename, []byte(`msgid "abc"`), 0644), `failed to write file`) {
+		return
+	}
+
+	select {
+	case name := <-src.Changes():
+		assert.Equal(t, filepath.Join("en", "LC_MESSAGES", "default.po"), name)
+	case <-time.After(5 * time.Second):
+		t.Errorf("timed out waiting for change notification")
+	}
+
+	// Polling goes on while the changes are not received, and a file
+	// that changed several times is reported once
+	other := filepath.Join(dirname, "errors.po")
+	for _, content := range []string{`msgid "a"`, `msgid "abc"`, `msgid "abcdef"`} {
+		if !assert.NoError(t, ioutil.WriteFile(other, []byte(content), 0644), `failed to write file`) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	select {
+	case name := <-src.Changes():
+		assert.Equal(t, filepath.Join("en", "LC_MESSAGES", "errors.po"), name)
+	case <-time.After(5 * time.Second):
+		t.Errorf("timed out waiting for change notification")
+	}
+	select {
+	case name := <-src.Changes():
+		t.Errorf("unexpected change notification for %s", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	src.Close()
+	for range src.Changes() {
+	}
+
+	// Intervals that are not positive keep the default
+	for _, d := range []time.Duration{0, -time.Second} {
+		src := NewWatchingFileSystemSource(tmpdir, WithPollInterval(d))
+		assert.Equal(t, time.Second, src.interval, `interval should be the default for `+d.String())
+		src.Close()
+		for range src.Changes() {
+		}
+	}
+}
+
+func TestGzipSource(t *testing.T) {
+	po := []byte(`
+msgid "Hello"
+msgstr "Bonjour"
+`)
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(po)
+	if !assert.NoError(t, w.Close(), `failed to compress catalog`) {
+		return
+	}
+
+	src := NewGzipSource(NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": buf.Bytes(),
+		"fr/LC_MESSAGES/plain.po":   po,
+		"fr/LC_MESSAGES/broken.po":  buf.Bytes()[:buf.Len()/2],
+	}))
+
+	data, err := src.ReadFile("fr/LC_MESSAGES/default.po")
+	if assert.NoError(t, err, `reading a compressed file should succeed`) {
+		assert.Equal(t, po, data, `compressed file should be decompressed`)
+	}
+
+	data, err = src.ReadFile("fr/LC_MESSAGES/plain.po")
+	if assert.NoError(t, err, `reading an uncompressed file should succeed`) {
+		assert.Equal(t, po, data, `uncompressed file should be returned as is`)
+	}
+
+	_, err = src.ReadFile("fr/LC_MESSAGES/broken.po")
+	assert.Error(t, err, `reading a truncated file should fail`)
+
+	_, err = src.ReadFile("fr/LC_MESSAGES/missing.po")
+	assert.True(t, os.IsNotExist(err), `missing files should be reported as such`)
+
+	l := NewLocale("fr", WithSource(src))
+	if assert.NoError(t, l.AddDomain("default"), `AddDomain should succeed`) {
+		assert.Equal(t, "Bonjour", l.Get("Hello"))
+	}
+
+	// Listing is forwarded to the wrapped Source
+	noList := NewGzipSource(SourceFunc(src.ReadFile))
+	_, err = noList.ReadDir(".")
+	assert.True(t, errors.Is(err, ErrReadDirNotSupported), `ReadDir should report ErrReadDirNotSupported`)
+	_, err = noList.Glob("*")
+	assert.True(t, errors.Is(err, ErrGlobNotSupported), `Glob should report ErrGlobNotSupported`)
+
+	matches, err := NewGzipSource(dirOnlySource{NewMapSource(map[string][]byte{
+		"fr/LC_MESSAGES/default.po": po,
+		"fr/LC_MESSAGES/errors.po":  po,
+		"fr/LC_MESSAGES/errors.mo":  nil,
+		"de/LC_MESSAGES/errors.po":  po,
+	})}).Glob(filepath.Join("*", "LC_MESSAGES", "*.po"))
+	if assert.NoError(t, err, `Glob should read the directories`) {
+		assert.Equal(t, []string{
+			filepath.Join("de", "LC_MESSAGES", "errors.po"),
+			filepath.Join("fr", "LC_MESSAGES", "default.po"),
+			filepath.Join("fr", "LC_MESSAGES", "errors.po"),
+		}, matches)
+	}
+}
+
+func TestArchiveSources(t *testing.T) {
+	files := map[string]string{
+		"fr/LC_MESSAGES/default.po": `
+msgid "Hello"
+msgstr "Bonjour"
+`,
+		"de/LC_MESSAGES/default.po": `
+msgid "Hello"
+msgstr "Hallo"
+`,
+	}
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if !assert.NoError(t, err, `failed to create zip entry`) {
+			return
+		}
+		w.Write([]byte(content))
+	}
+	if !assert.NoError(t, zw.Close(), `failed to write zip archive`) {
+		return
+	}
+
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	tw.WriteHeader(&tar.Header{Name: "fr/", Typeflag: tar.TypeDir, Mode: 0755})
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	if !assert.NoError(t, tw.Close(), `failed to write tar archive`) {
+		return
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len()))
+	if !assert.NoError(t, err, `failed to read zip archive`) {
+		return
+	}
+	tarSrc, err := NewTarSource(bytes.NewReader(tarBuf.Bytes()))
+	if !assert.NoError(t, err, `NewTarSource should succeed`) {
+		return
+	}
+
+	sources := map[string]Source{
+		"zip": NewZipSource(zr),
+		"tar": tarSrc,
+	}
+	for name, src := range sources {
+		data, err := src.ReadFile("fr/LC_MESSAGES/default.po")
+		if assert.NoError(t, err, name+": ReadFile should succeed") {
+			assert.Equal(t, files["fr/LC_MESSAGES/default.po"], string(data), name+": ReadFile should return the content of the entry")
+		}
+
+		_, err = src.ReadFile("fr/LC_MESSAGES/missing.po")
+		assert.True(t, os.IsNotExist(err), name+": missing files should be reported as such")
+
+		l := NewLocale("de", WithSource(src))
+		if assert.NoError(t, l.AddDomain("default"), name+": AddDomain should succeed") {
+			assert.Equal(t, "Hallo", l.Get("Hello"), name+": translation should be found")
+		}
+	}
+
+	_, err = NewTarSource(bytes.NewReader(tarBuf.Bytes()[:100]))
+	assert.Error(t, err, `NewTarSource should fail on a truncated archive`)
+}
+
+func TestFileSystemSourceGlob(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "go-gettext-")
+	if !assert.NoError(t, err, `failed to create temporary directory`) {
+		return
+	}
+	defer os.RemoveAll(tmpdir)
+
+	dir := filepath.Join(tmpdir, "fr", "LC_MESSAGES")
+	if !assert.NoError(t, os.MkdirAll(dir, 0755), `failed to create directory`) {
+		return
+	}
+	for _, name := range []string{"svc-a.po", "svc-b.mo", "other.po"} {
+		if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644), `failed to write file`) {
+			return
+		}
+	}
+
+	matches, err := NewFileSystemSource(tmpdir).Glob(filepath.Join("fr", "LC_MESSAGES", "svc-*"))
+	if assert.NoError(t, err, `Glob should succeed`) {
+		assert.Equal(t, []string{filepath.Join("fr", "LC_MESSAGES", "svc-a.po"), filepath.Join("fr", "LC_MESSAGES", "svc-b.mo")}, matches)
+	}
+
+	_, err = NewFileSystemSource(tmpdir).Glob("[")
+	assert.Error(t, err, `Glob should fail on a malformed pattern`)
+}
+
+func TestDirSource(t *testing.T) {
+	files := map[string][]byte{
+		"fr/LC_MESSAGES/default.po": nil,
+		"fr/LC_MESSAGES/errors.mo":  nil,
+		"fr_CA/default.po":          nil,
+		"README":                    nil,
+	}
+
+	tmpdir, err := ioutil.TempDir("", "go-gettext-")
+	if !assert.NoError(t, err, `failed to create temporary directory`) {
+		return
+	}
+	defer os.RemoveAll(tmpdir)
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for name := range files {
+		fn := filepath.Join(tmpdir, filepath.FromSlash(name))
+		if !assert.NoError(t, os.MkdirAll(filepath.Dir(fn), 0755), `failed to create directory`) {
+			return
+		}
+		if !assert.NoError(t, ioutil.WriteFile(fn, nil, 0644), `failed to write file`) {
+			return
+		}
+		if _, err := zw.Create(name); !assert.NoError(t, err, `failed to create zip entry`) {
+			return
+		}
+	}
+	if !assert.NoError(t, zw.Close(), `failed to write zip archive`) {
+		return
+	}
+	zr, err := zip.NewReader(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len()))
+	if !assert.NoError(t, err, `failed to read zip archive`) {
+		return
+	}
+
+	sources := map[string]DirSource{
+		"filesystem": NewFileSystemSource(tmpdir),
+		"map":        NewMapSource(files),
+		"zip":        NewZipSource(zr),
+	}
+	for name, src := range sources {
+		entries, err := src.ReadDir(".")
+		if assert.NoError(t, err, name+": ReadDir should succeed") {
+			assert.Equal(t, []string{"README", "fr", "fr_CA"}, entries, name+": entries of the root")
+		}
+
+		entries, err = src.ReadDir("fr/LC_MESSAGES")
+		if assert.NoError(t, err, name+": ReadDir should succeed") {
+			assert.Equal(t, []string{"default.po", "errors.mo"}, entries, name+": entries of a subdirectory")
+		}
+
+		_, err = src.ReadDir("de")
+		assert.True(t, os.IsNotExist(err), name+": missing directories should be reported as such")
+	}
+}
@@ -0,0 +1,147 @@
+package gettext
+
+import (
+	"context"
+	"io"
+)
+
+type translatorKey struct{}
+
+// NewTranslatorContext returns a copy of ctx that carries the given
+// Translator
+func NewTranslatorContext(ctx context.Context, t *Translator) context.Context {
+	return context.WithValue(ctx, translatorKey{}, t)
+}
+
+// TranslatorFromContext returns the Translator stored in ctx by
+// NewTranslatorContext. If there is none, a Translator that uses a
+// NullLocale is returned, so the result is always safe to use.
+func TranslatorFromContext(ctx context.Context) *Translator {
+	if t, ok := ctx.Value(translatorKey{}).(*Translator); ok && t != nil {
+		return t
+	}
+	return &Translator{locale: &NullLocale{}}
+}
+
+// Lang returns the name of the locale that was selected, or an empty
+// string if none of the requested languages were available
+func (t *Translator) Lang() string {
+	return t.lang
+}
+
+// Locale returns the Locale that was selected
+func (t *Translator) Locale() Locale {
+	return t.locale
+}
+
+// Get uses the default domain to return the corresponding translation of a
+// given string.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (t *Translator) Get(str string, vars ...interface{}) string {
+	return t.locale.Get(str, vars...)
+}
+
+// GetRaw uses the default domain to return the translation of the given
+// string without formatting it.
+func (t *Translator) GetRaw(str string) string {
+	return getRaw(t.locale, str)
+}
+
+// GetRawD returns the translation of the given string in the given
+// domain without formatting it.
+func (t *Translator) GetRawD(dom, str string) string {
+	return getRawD(t.locale, dom, str)
+}
+
+// GetContext is like Get, but honors the cancellation of ctx when the
+// domain has to be loaded lazily.
+func (t *Translator) GetContext(ctx context.Context, str string, vars ...interface{}) string {
+	return getContext(ctx, t.locale, str, vars...)
+}
+
+// GetDContext is like GetD, but honors the cancellation of ctx when the
+// domain has to be loaded lazily.
+func (t *Translator) GetDContext(ctx context.Context, dom, str string, vars ...interface{}) string {
+	return getDContext(ctx, t.locale, dom, str, vars...)
+}
+
+// GetN retrieves the (N)th plural form of translation for the given string in
+// the default domain.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (t *Translator) GetN(str, plural string, n int, vars ...interface{}) string {
+	return t.locale.GetN(str, plural, n, vars...)
+}
+
+// GetD returns the corresponding translation in the given domain for the given string.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (t *Translator) GetD(dom, str string, vars ...interface{}) string {
+	return t.locale.GetD(dom, str, vars...)
+}
+
+// GetDErr is like GetD, but also returns an error if the domain has not
+// been loaded, or could not be loaded.
+func (t *Translator) GetDErr(dom, str string, vars ...interface{}) (string, error) {
+	return getDErr(t.locale, dom, str, vars...)
+}
+
+// TryGetD is like GetD, but the second return value reports whether a
+// translation was found.
+func (t *Translator) TryGetD(dom, str string, vars ...interface{}) (string, bool) {
+	return tryGetD(t.locale, dom, str, vars...)
+}
+
+// GetND retrieves the (N)th plural form of translation in the given domain for the given string.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (t *Translator) GetND(dom, str, plural string, n int, vars ...interface{}) string {
+	return t.locale.GetND(dom, str, plural, n, vars...)
+}
+
+// GetC uses the default domain to return the corresponding translation of
+// the given string in the given context.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (t *Translator) GetC(str, ctx string, vars ...interface{}) string {
+	return t.locale.GetC(str, ctx, vars...)
+}
+
+// GetNC retrieves the (N)th plural form of translation for the given string
+// in the given context in the default domain.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (t *Translator) GetNC(str, plural string, n int, ctx string, vars ...interface{}) string {
+	return t.locale.GetNC(str, plural, n, ctx, vars...)
+}
+
+// GetDC returns the corresponding translation in the given domain for the given string in the given context.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (t *Translator) GetDC(dom, str, ctx string, vars ...interface{}) string {
+	return t.locale.GetDC(dom, str, ctx, vars...)
+}
+
+// GetNDC retrieves the (N)th plural form of translation in the given domain for the given string in the given context.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (t *Translator) GetNDC(dom, str, plural string, n int, ctx string, vars ...interface{}) string {
+	return t.locale.GetNDC(dom, str, plural, n, ctx, vars...)
+}
+
+// Fprintf writes the translation of the given string in the default
+// domain to w, formatted with the given values.
+func (t *Translator) Fprintf(w io.Writer, str string, vars ...interface{}) (int, error) {
+	return fprintf(w, t.locale, str, vars...)
+}
+
+// FprintfD writes the translation of the given string in the given
+// domain to w, formatted with the given values.
+func (t *Translator) FprintfD(w io.Writer, dom, str string, vars ...interface{}) (int, error) {
+	return fprintfD(w, t.locale, dom, str, vars...)
+}
+
+// FprintfC writes the translation of the given string in the given
+// context in the default domain to w, formatted with the given values.
+func (t *Translator) FprintfC(w io.Writer, str, ctx string, vars ...interface{}) (int, error) {
+	return fprintfC(w, t.locale, str, ctx, vars...)
+}
+
+// FprintfDC writes the translation of the given string in the given
+// domain and context to w, formatted with the given values.
+func (t *Translator) FprintfDC(w io.Writer, dom, str, ctx string, vars ...interface{}) (int, error) {
+	return fprintfDC(w, t.locale, dom, str, ctx, vars...)
+}
@@ -0,0 +1,137 @@
+package gettext
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NewWatchingFileSystemSource creates a new Source that reads files from
+// the given directory, just like FileSystemSource, but also watches the
+// directory for changes. The names of the files that were changed (relative
+// to dir, e.g. "en/LC_MESSAGES/default.po") are sent to the channel
+// returned by Changes(), so that the affected domains can be reloaded.
+//
+// The directory is polled for changes, so no platform specific
+// notification mechanism is required. Call Close() to stop watching.
+//
+// Possible options include:
+// * WithPollInterval: how often the directory should be checked
+func NewWatchingFileSystemSource(dir string, options ...Option) *WatchingFileSystemSource {
+	interval := time.Second
+	for _, o := range options {
+		switch o.Name() {
+		case "poll_interval":
+			// time.NewTicker panics if the interval is not positive
+			if d := o.Value().(time.Duration); d > 0 {
+				interval = d
+			}
+		}
+	}
+
+	w := &WatchingFileSystemSource{
+		FileSystemSource: FileSystemSource{root: dir},
+		changes:          make(chan string),
+		done:             make(chan struct{}),
+		interval:         interval,
+	}
+	w.files = w.scan()
+
+	go w.watch()
+	return w
+}
+
+// Changes returns the channel where the names of changed files are sent.
+// The channel is closed when the source is closed.
+//
+// The directory is polled even if the channel is not drained. The names
+// of the files that changed in the meantime are kept until they are
+// received, and a file that changed several times is only reported once.
+func (w *WatchingFileSystemSource) Changes() <-chan string {
+	return w.changes
+}
+
+// Close stops watching the directory
+func (w *WatchingFileSystemSource) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	return nil
+}
+
+func (w *WatchingFileSystemSource) watch() {
+	defer close(w.changes)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// Names that have not been received yet, in order
+	var pending []string
+	queued := make(map[string]bool)
+
+	for {
+		// Sending is only enabled when there is something to send
+		var out chan string
+		var next string
+		if len(pending) > 0 {
+			out, next = w.changes, pending[0]
+		}
+
+		select {
+		case <-w.done:
+			return
+		case out <- next:
+			delete(queued, next)
+			pending = pending[1:]
+			continue
+		case <-ticker.C:
+		}
+
+		files := w.scan()
+
+		var changed []string
+		for name, st := range files {
+			if prev, ok := w.files[name]; !ok || prev != st {
+				changed = append(changed, name)
+			}
+		}
+		for name := range w.files {
+			if _, ok := files[name]; !ok {
+				changed = append(changed, name)
+			}
+		}
+		w.files = files
+
+		for _, name := range changed {
+			if !queued[name] {
+				queued[name] = true
+				pending = append(pending, name)
+			}
+		}
+	}
+}
+
+// scan collects the modification time and size of all catalog files
+// under the root directory
+func (w *WatchingFileSystemSource) scan() map[string]fileStat {
+	files := make(map[string]fileStat)
+	filepath.Walk(w.root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return nil
+		}
+
+		switch filepath.Ext(path) {
+		case ".po", ".mo":
+		default:
+			return nil
+		}
+
+		name, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return nil
+		}
+		files[name] = fileStat{modTime: fi.ModTime(), size: fi.Size()}
+		return nil
+	})
+	return files
+}
@@ -0,0 +1,194 @@
+package gettext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewTemplate creates an empty Po object that is meant to be written as
+// a template (.pot) file, for example after filling it with Extract. The
+// headers are pre-populated with the same defaults as xgettext, so that
+// the result is accepted by msginit and translation tools.
+//
+// The options accepted by NewParser may be specified.
+func NewTemplate(options ...Option) *Po {
+	po := NewPo(options...)
+	po.headers = []header{
+		{name: "Project-Id-Version", value: "PACKAGE VERSION"},
+		{name: "Report-Msgid-Bugs-To", value: ""},
+		{name: "POT-Creation-Date", value: time.Now().Format("2006-01-02 15:04-0700")},
+		{name: "PO-Revision-Date", value: "YEAR-MO-DA HO:MI+ZONE"},
+		{name: "Last-Translator", value: "FULL NAME <EMAIL@ADDRESS>"},
+		{name: "Language-Team", value: "LANGUAGE <LL@li.org>"},
+		{name: "Language", value: ""},
+		{name: "MIME-Version", value: "1.0"},
+		{name: "Content-Type", value: "text/plain; charset=UTF-8"},
+		{name: "Content-Transfer-Encoding", value: "8bit"},
+		{name: "Plural-Forms", value: "nplurals=INTEGER; plural=EXPRESSION;"},
+	}
+	return po
+}
+
+// commentPrefixes maps the types of comments to their markers
+var commentPrefixes = map[string]string{
+	CommentTranslator: "#",
+	CommentExtracted:  "#.",
+	CommentReference:  "#:",
+	CommentFlag:       "#,",
+	CommentPrevious:   "#|",
+	CommentObsolete:   "#~|",
+}
+
+// WritePO writes the catalog to w in the .po format. The header entry
+// comes first, followed by the entries without a context sorted by
+// msgid, the entries with a context sorted by msgctxt and msgid, and
+// finally the obsolete entries in their original order. The output only
+// depends on the content of the catalog, so it is suitable for golden
+// file tests.
+func (po *Po) WritePO(w io.Writer) error {
+	defer po.runlock(po.rlock())
+
+	bw := bufio.NewWriter(w)
+
+	// The headers are always written on separate lines, as the parser
+	// only recognizes them in that form
+	if len(po.headers) > 0 {
+		bw.WriteString("msgid \"\"\nmsgstr \"\"\n")
+		for _, h := range po.headers {
+			bw.WriteString(quote(h.name+": "+h.value+"\n") + "\n")
+		}
+	}
+
+	for _, id := range sortedKeys(po.translations) {
+		bw.WriteString("\n")
+		writeEntry(bw, po.translations[id], "")
+	}
+
+	ctxs := make([]string, 0, len(po.contexts))
+	for ctx := range po.contexts {
+		ctxs = append(ctxs, ctx)
+	}
+	sort.Strings(ctxs)
+
+	for _, ctx := range ctxs {
+		m := po.contexts[ctx]
+		for _, id := range sortedKeys(m) {
+			bw.WriteString("\n")
+			writeEntry(bw, m[id], "")
+		}
+	}
+
+	for _, t := range po.obsolete {
+		bw.WriteString("\n")
+		writeEntry(bw, t, "#~ ")
+	}
+
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, `po: failed to write`)
+	}
+	return nil
+}
+
+// writeEntry writes a single entry. Each line of the entry, except for
+// the comments, is prefixed with prefix
+func writeEntry(w *bufio.Writer, t *translation, prefix string) {
+	for _, c := range t.Comments {
+		marker, ok := commentPrefixes[c.Type]
+		if !ok {
+			marker = "#"
+		}
+		if c.Text == "" {
+			w.WriteString(marker + "\n")
+		} else {
+			w.WriteString(marker + " " + c.Text + "\n")
+		}
+	}
+
+	if t.ctx != "" {
+		writeString(w, prefix, "msgctxt", t.ctx)
+	}
+	writeString(w, prefix, "msgid", t.id)
+
+	if t.PluralID == "" {
+		v, _ := t.Trs.Get(0)
+		writeString(w, prefix, "msgstr", v)
+		return
+	}
+
+	writeString(w, prefix, "msgid_plural", t.PluralID)
+	n := t.Trs.Len()
+	if n < 2 {
+		n = 2
+	}
+	for i := 0; i < n; i++ {
+		v, _ := t.Trs.Get(i)
+		writeString(w, prefix, "msgstr["+strconv.Itoa(i)+"]", v)
+	}
+}
+
+// writeString writes a keyword and its string value. Values that contain
+// newlines are split after each newline, as msgmerge does
+func writeString(w *bufio.Writer, prefix, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) <= 1 {
+		w.WriteString(prefix + keyword + " " + quote(s) + "\n")
+		return
+	}
+
+	w.WriteString(prefix + keyword + " \"\"\n")
+	for _, line := range lines {
+		w.WriteString(prefix + quote(line) + "\n")
+	}
+}
+
+// quote returns s as a double-quoted PO string. It is the inverse of
+// unquote
+func quote(s string) string {
+	var buf strings.Builder
+	buf.Grow(len(s) + 2)
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\a':
+			buf.WriteString(`\a`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\v':
+			buf.WriteString(`\v`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				// Always use 3 digits, so that a following digit
+				// is not taken as part of the escape sequence
+				fmt.Fprintf(&buf, `\%03o`, c)
+				continue
+			}
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
@@ -0,0 +1,242 @@
+package gettext
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePO(t *testing.T) {
+	str := `msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+"X-Custom: value\n"
+
+# Translator comment
+#. Extracted comment
+#: src/main.c:10
+#, fuzzy, c-format
+#| msgid "Old %d"
+msgid "New %d"
+msgstr "Nouveau %d"
+
+msgid "Escapes \"quoted\"\t\\"
+msgstr "Échappements \"entre guillemets\"\t\\"
+
+msgid ""
+"First line\n"
+"Second line"
+msgstr ""
+"Première ligne\n"
+"Deuxième ligne"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+#~| msgid "Older"
+#~ msgid "Old"
+#~ msgstr "Vieux"
+`
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, po.WritePO(&buf), `WritePO should succeed`) {
+		return
+	}
+
+	expected := `msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+"X-Custom: value\n"
+
+msgid "Escapes \"quoted\"\t\\"
+msgstr "Échappements \"entre guillemets\"\t\\"
+
+msgid ""
+"First line\n"
+"Second line"
+msgstr ""
+"Première ligne\n"
+"Deuxième ligne"
+
+# Translator comment
+#. Extracted comment
+#: src/main.c:10
+#, fuzzy, c-format
+#| msgid "Old %d"
+msgid "New %d"
+msgstr "Nouveau %d"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+#~| msgid "Older"
+#~ msgid "Old"
+#~ msgstr "Vieux"
+`
+	assert.Equal(t, expected, buf.String())
+
+	// The output should parse to the same catalog
+	po2, err := NewParser(WithStrictParsing(true)).ParseString(buf.String())
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+	var buf2 bytes.Buffer
+	if assert.NoError(t, po2.WritePO(&buf2), `WritePO should succeed`) {
+		assert.Equal(t, buf.String(), buf2.String(), `round trip should be stable`)
+	}
+	assert.Equal(t, "fr", po2.Language())
+	assert.Equal(t, "value", po2.Header("x-custom"))
+}
+
+func TestWritePOObsoleteContext(t *testing.T) {
+	str := `msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+# Translator comment
+#~| msgctxt "old menu"
+#~| msgid "One old file"
+#~ msgctxt "menu"
+#~ msgid "One file"
+#~ msgid_plural "%d files"
+#~ msgstr[0] "Un fichier"
+#~ msgstr[1] "%d fichiers"
+
+#~ msgctxt ""
+#~ "toolbar\n"
+#~ "button"
+#~ msgid "Close"
+#~ msgstr "Fermer"
+
+#~ msgctxt "menu"
+#~ msgid "Open"
+#~ msgstr "Ouvrir (ancien)"
+`
+
+	po, err := NewParser(WithStrictParsing(true)).ParseString(str)
+	if !assert.NoError(t, err, `ParseString should succeed`) {
+		return
+	}
+
+	obsolete := po.ObsoleteMessages()
+	if !assert.Len(t, obsolete, 3, `there should be 3 obsolete entries`) {
+		return
+	}
+	assert.Equal(t, Message{
+		Context:         "menu",
+		ID:              "One file",
+		PluralID:        "%d files",
+		Strings:         []string{"Un fichier", "%d fichiers"},
+		PreviousContext: "old menu",
+		PreviousID:      "One old file",
+		Comments: []Comment{
+			{Type: CommentTranslator, Text: "Translator comment"},
+			{Type: CommentObsolete, Text: `msgctxt "old menu"`},
+			{Type: CommentObsolete, Text: `msgid "One old file"`},
+		},
+	}, obsolete[0])
+	assert.Equal(t, "toolbar\nbutton", obsolete[1].Context)
+	assert.Equal(t, "Ouvrir (ancien)", obsolete[2].Strings[0])
+
+	// Obsolete entries must not be used or replace current ones
+	assert.Equal(t, "Ouvrir", po.GetC("Open", "menu"))
+	assert.Equal(t, "%d files", po.GetNC("One file", "%d files", 2, "menu"), `obsolete entries should not be used`)
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, po.WritePO(&buf), `WritePO should succeed`) {
+		return
+	}
+	assert.Equal(t, str, buf.String(), `round trip should preserve obsolete entries`)
+}
+
+func TestWritePOStable(t *testing.T) {
+	ids := []string{"c", "a", "b"}
+	ctxs := []string{"", "y", "x"}
+
+	var outputs []string
+	for i := 0; i < 3; i++ {
+		po := NewTemplate()
+		for j := range ids {
+			id := ids[(i+j)%len(ids)]
+			for k := range ctxs {
+				po.Set(ctxs[(i+k)%len(ctxs)], id, "", []string{strings.ToUpper(id)})
+			}
+		}
+
+		var buf bytes.Buffer
+		if !assert.NoError(t, po.WritePO(&buf), `WritePO should succeed`) {
+			return
+		}
+		outputs = append(outputs, buf.String())
+	}
+
+	assert.Equal(t, outputs[0], outputs[1], `output should not depend on insertion order`)
+	assert.Equal(t, outputs[0], outputs[2], `output should not depend on insertion order`)
+}
+
+func TestQuote(t *testing.T) {
+	for _, s := range []string{"", "plain", "\"\\\n\t\r\a\b\f\v", "\x01\x7f1", "日本語"} {
+		v, err := unquote(quote(s))
+		if assert.NoError(t, err, `unquote should succeed for `+quote(s)) {
+			assert.Equal(t, s, v)
+		}
+	}
+}
+
+func TestNewTemplate(t *testing.T) {
+	po := NewTemplate()
+	po.Set("", "Hello", "", []string{""})
+	po.Set("", "One file", "%d files", nil)
+
+	assert.Equal(t, "text/plain; charset=UTF-8", po.Header("Content-Type"))
+	assert.NotEmpty(t, po.Header("POT-Creation-Date"))
+
+	po.SetHeader("Project-Id-Version", "myapp 1.0")
+	po.SetHeader("X-Generator", "test")
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, po.WritePO(&buf), `WritePO should succeed`) {
+		return
+	}
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "msgid \"\"\nmsgstr \"\"\n\"Project-Id-Version: myapp 1.0\\n\"\n"), `headers should come first`)
+	assert.Contains(t, out, "\"Content-Transfer-Encoding: 8bit\\n\"\n\"Plural-Forms: nplurals=INTEGER; plural=EXPRESSION;\\n\"\n\"X-Generator: test\\n\"\n")
+	assert.Contains(t, out, "msgid \"One file\"\nmsgid_plural \"%d files\"\nmsgstr[0] \"\"\nmsgstr[1] \"\"\n")
+
+	parsed, err := NewParser().ParseString(out)
+	if assert.NoError(t, err, `ParseString should succeed`) {
+		assert.Equal(t, "myapp 1.0", parsed.Header("Project-Id-Version"))
+		assert.Equal(t, "Hello", parsed.Get("Hello"))
+	}
+
+	po.SetHeader("Language", "fr")
+	po.SetHeader("Plural-Forms", "nplurals=2; plural=(n > 1);")
+	assert.Equal(t, "fr", po.Language())
+	assert.Equal(t, 2, po.NPlurals())
+}